@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 
@@ -223,12 +224,16 @@ func processWithRegex(policy Policy, data []byte, rgPath string, isObserve bool)
 }
 func executeAssureForAPI(policy Policy, rgPath, filePath string) (bool, error) {
 	// Create a temporary file to store the search patterns
-	searchPatternFile, err := createSearchPatternFile(policy.Regex, NormalizeFilename(policy.ID))
+	searchPatternFile, err := createSearchPatternFile(regexPatternStrings(policy.Regex), NormalizeFilename(policy.ID))
 	if err != nil {
 		return false, fmt.Errorf("error creating search pattern file: %w", err)
 	}
 	defer os.Remove(searchPatternFile)
 
+	if useNativeEngine() {
+		return nativeSearch(regexPatternStrings(policy.Regex), []string{filePath}, io.Discard)
+	}
+
 	// Prepare the ripgrep command
 	args := []string{
 		"--pcre2",
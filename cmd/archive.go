@@ -0,0 +1,427 @@
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// maxArchiveTargetSize bounds the content extracted from an archive or image target
+	maxArchiveTargetSize = 4 << 30
+
+	// archivePathSeparator separates the archive from the path inside it in result URIs, like image.tar!/layer1/etc/passwd
+	archivePathSeparator = "!/"
+)
+
+// archiveTarget is an archive or image target extracted to a temporary directory,
+// results under root are reported with virtual paths prefixed by name. Index snapshots of staged
+// files set realRoot instead, their results are reported under the audited target
+type archiveTarget struct {
+	name     string
+	root     string
+	realRoot string
+}
+
+// virtualPrefix replaces the path of root in result URIs and messages
+func (a *archiveTarget) virtualPrefix() string {
+	if a.realRoot != "" {
+		return strings.TrimSuffix(filepath.ToSlash(a.realRoot), "/") + "/"
+	}
+	return a.name + archivePathSeparator
+}
+
+var activeArchiveTarget *archiveTarget
+
+// resolveArchiveTarget extracts tar, tar.gz, zip archives, docker save tarballs and OCI image layouts
+// and returns the directory to audit. Plain directories are returned unchanged.
+func resolveArchiveTarget(target string) (string, func(), error) {
+	noop := func() {}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", noop, fmt.Errorf("target not found: %w", err)
+	}
+	if info.IsDir() && !isOCILayout(target) {
+		return target, noop, nil
+	}
+
+	root, err := os.MkdirTemp("", "intercept-target-")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create target directory: %w", err)
+	}
+	cleanup := func() {
+		activeArchiveTarget = nil
+		os.RemoveAll(root)
+	}
+
+	if err := extractArchiveTarget(target, info.IsDir(), root); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+
+	activeArchiveTarget = &archiveTarget{name: filepath.Base(filepath.Clean(target)), root: root}
+	log.Info().Str("target", target).Msg("Auditing archive target")
+	return root, cleanup, nil
+}
+
+func extractArchiveTarget(target string, isDir bool, root string) error {
+	if isDir {
+		return extractImageLayers(target, root)
+	}
+
+	kind, err := detectArchiveKind(target)
+	if err != nil {
+		return err
+	}
+	limit := &extractLimit{remaining: maxArchiveTargetSize}
+
+	switch kind {
+	case "zip":
+		return extractZip(target, root, limit)
+	case "tar", "tar.gz":
+		// images are unpacked first, their layers are extracted from the unpacked blobs
+		staging, err := os.MkdirTemp("", "intercept-image-")
+		if err != nil {
+			return fmt.Errorf("failed to create staging directory: %w", err)
+		}
+		defer os.RemoveAll(staging)
+
+		if err := extractTarFile(target, staging, limit); err != nil {
+			return err
+		}
+		if isOCILayout(staging) || isDockerArchive(staging) {
+			return extractImageLayers(staging, root)
+		}
+		if err := os.Remove(root); err != nil {
+			return err
+		}
+		return os.Rename(staging, root)
+	}
+	return fmt.Errorf("unsupported target %s, expected a directory, tar, tar.gz, zip, docker save tarball or OCI layout", target)
+}
+
+// detectArchiveKind looks at the magic bytes, extensions are not reliable for release artifacts
+func detectArchiveKind(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, _ := io.ReadFull(f, header)
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")):
+		return "zip", nil
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return "tar.gz", nil
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return "tar", nil
+	}
+	return "", fmt.Errorf("unsupported target %s, expected a directory, tar, tar.gz, zip, docker save tarball or OCI layout", file)
+}
+
+// extractLimit is shared by all the entries of a target so nested layers count against the same budget
+type extractLimit struct {
+	remaining int64
+}
+
+func (l *extractLimit) copy(dst io.Writer, src io.Reader) error {
+	n, err := io.CopyN(dst, src, l.remaining+1)
+	l.remaining -= n
+	if l.remaining < 0 {
+		return fmt.Errorf("target exceeds %d bytes once extracted", int64(maxArchiveTargetSize))
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// archiveEntryPath returns where an entry is extracted, entries escaping dest are rejected
+func archiveEntryPath(dest, name string) (string, bool) {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	if name == "/" {
+		return "", false
+	}
+	return filepath.Join(dest, filepath.FromSlash(name[1:])), true
+}
+
+func writeArchiveEntry(target string, r io.Reader, limit *extractLimit) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err := limit.copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func extractTarFile(file, dest string, limit *extractLimit) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return extractTar(f, dest, limit, false)
+}
+
+// extractTar unpacks regular files and directories, links and devices are skipped.
+// Layers drop the whiteout markers of deleted files.
+func extractTar(r io.Reader, dest string, limit *extractLimit, layer bool) error {
+	reader, err := decompressedReader(r)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading tar: %w", err)
+		}
+
+		target, ok := archiveEntryPath(dest, header.Name)
+		if !ok {
+			continue
+		}
+		if layer && strings.HasPrefix(path.Base(header.Name), ".wh.") {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveEntry(target, tr, limit); err != nil {
+				return err
+			}
+		default:
+			log.Debug().Str("entry", header.Name).Msg("archive entry skipped, not a regular file")
+		}
+	}
+}
+
+// decompressedReader transparently handles gzip compressed tar streams
+func decompressedReader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+	return buffered, nil
+}
+
+func extractZip(file, dest string, limit *extractLimit) error {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return fmt.Errorf("error reading zip: %w", err)
+	}
+	defer zr.Close()
+
+	for _, entry := range zr.File {
+		target, ok := archiveEntryPath(dest, entry.Name)
+		if !ok {
+			continue
+		}
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !entry.Mode().IsRegular() {
+			log.Debug().Str("entry", entry.Name).Msg("archive entry skipped, not a regular file")
+			continue
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		err = writeArchiveEntry(target, rc, limit)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isOCILayout(dir string) bool {
+	_, errLayout := os.Stat(filepath.Join(dir, "oci-layout"))
+	_, errIndex := os.Stat(filepath.Join(dir, "index.json"))
+	return errLayout == nil && errIndex == nil
+}
+
+// isDockerArchive recognizes docker save tarballs by their manifest.json, a list of images and their layers
+func isDockerArchive(dir string) bool {
+	_, err := dockerManifestLayers(dir)
+	return err == nil
+}
+
+func dockerManifestLayers(dir string) ([]string, error) {
+	var manifests []struct {
+		Layers []string `json:"Layers"`
+	}
+	if err := readImageJSON(dir, "manifest.json", &manifests); err != nil {
+		return nil, err
+	}
+	var layers []string
+	for _, manifest := range manifests {
+		for _, layer := range manifest.Layers {
+			if layerPath, ok := archiveEntryPath(dir, layer); ok {
+				layers = appendUnique(layers, layerPath)
+			}
+		}
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("no image in manifest.json")
+	}
+	return layers, nil
+}
+
+// extractImageLayers extracts each layer of the images of an unpacked docker save tarball or OCI layout
+// to dest/layer<N>, N following the layer order of the manifests
+func extractImageLayers(imageDir, dest string) error {
+	layers, err := imageLayerPaths(imageDir)
+	if err != nil {
+		return err
+	}
+	if len(layers) == 0 {
+		return fmt.Errorf("image %s has no layers", imageDir)
+	}
+
+	limit := &extractLimit{remaining: maxArchiveTargetSize}
+	for i, layer := range layers {
+		f, err := os.Open(layer)
+		if err != nil {
+			return fmt.Errorf("image layer not found: %w", err)
+		}
+		err = extractTar(f, filepath.Join(dest, fmt.Sprintf("layer%d", i+1)), limit, true)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("error extracting layer %d: %w", i+1, err)
+		}
+	}
+	log.Debug().Int("layers", len(layers)).Str("image", imageDir).Msg("Image layers extracted")
+	return nil
+}
+
+// imageLayerPaths lists the layer blobs of all the images, shared layers once
+func imageLayerPaths(imageDir string) ([]string, error) {
+	if layers, err := dockerManifestLayers(imageDir); err == nil {
+		return layers, nil
+	}
+
+	var layers []string
+
+	var index ociIndex
+	if err := readImageJSON(imageDir, "index.json", &index); err != nil {
+		return nil, err
+	}
+	descriptors := index.Manifests
+	for len(descriptors) > 0 {
+		descriptor := descriptors[0]
+		descriptors = descriptors[1:]
+
+		blob, ok := ociBlobPath(imageDir, descriptor.Digest)
+		if !ok {
+			return nil, fmt.Errorf("invalid digest %q", descriptor.Digest)
+		}
+		var manifest ociIndex
+		if err := readImageJSON(filepath.Dir(blob), filepath.Base(blob), &manifest); err != nil {
+			return nil, err
+		}
+		// nested indexes list one manifest per platform
+		descriptors = append(descriptors, manifest.Manifests...)
+		for _, layer := range manifest.Layers {
+			if strings.Contains(layer.MediaType, "zstd") {
+				return nil, fmt.Errorf("zstd compressed layers are not supported (%s)", layer.Digest)
+			}
+			if layerPath, ok := ociBlobPath(imageDir, layer.Digest); ok {
+				layers = appendUnique(layers, layerPath)
+			}
+		}
+	}
+	return layers, nil
+}
+
+// ociIndex covers both OCI image indexes and image manifests
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+}
+
+func ociBlobPath(imageDir, digest string) (string, bool) {
+	algorithm, hex, ok := strings.Cut(digest, ":")
+	if !ok || algorithm == "" || hex == "" || strings.ContainsAny(digest, "/\\.") {
+		return "", false
+	}
+	return filepath.Join(imageDir, "blobs", algorithm, hex), true
+}
+
+func readImageJSON(dir, name string, v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return fmt.Errorf("error reading image %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("error parsing image %s: %w", name, err)
+	}
+	return nil
+}
+
+// virtualTargetPath turns a path under the extracted archive into archive!/path, other paths are returned unchanged
+func virtualTargetPath(p string) string {
+	if activeArchiveTarget == nil || p == "" || p == "N/A" {
+		return p
+	}
+	rel, err := filepath.Rel(activeArchiveTarget.root, filepath.FromSlash(p))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return p
+	}
+	return activeArchiveTarget.virtualPrefix() + filepath.ToSlash(rel)
+}
+
+// virtualizeResultURIs reports results of archive targets with their nested location
+func virtualizeResultURIs(results []Result) []Result {
+	if activeArchiveTarget == nil {
+		return results
+	}
+	rootPrefix := filepath.ToSlash(activeArchiveTarget.root) + "/"
+	for i := range results {
+		for j := range results[i].Locations {
+			location := &results[i].Locations[j].PhysicalLocation.ArtifactLocation
+			location.URI = virtualTargetPath(location.URI)
+		}
+		results[i].Message.Text = strings.ReplaceAll(results[i].Message.Text, rootPrefix, activeArchiveTarget.virtualPrefix())
+	}
+	return results
+}
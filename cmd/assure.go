@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sync"
 )
 
@@ -23,14 +24,6 @@ func ProcessAssureType(policy Policy, rgPath string, targetDir string, filePaths
 }
 
 func executeAssure(policy Policy, rgPath string, targetDir string, filesToAssure []string) error {
-	// Create a temporary file to store the search patterns
-	searchPatternFile, err := createSearchPatternFile(policy.Regex, NormalizeFilename(policy.ID))
-	if err != nil {
-		log.Error().Err(err).Msg("error creating search pattern file")
-		return fmt.Errorf("error creating search pattern file: %w", err)
-	}
-	defer os.Remove(searchPatternFile) // Clean up the temporary file
-
 	// Prepare the ripgrep command for JSON output
 	jsonOutputFile := fmt.Sprintf("assure_results_%s.json", NormalizeFilename(policy.ID))
 
@@ -45,13 +38,12 @@ func executeAssure(policy Policy, rgPath string, targetDir string, filesToAssure
 		log.Error().Err(err).Msg("error creating JSON output file")
 		return fmt.Errorf("error creating JSON output file: %w", err)
 	}
+	defer redactDebugOutput(policy, jsonOutputFile)
 	defer jsonoutfile.Close()
 
 	writer := bufio.NewWriter(jsonoutfile)
 	defer writer.Flush()
 
-	processedIgnorePatterns := processIgnorePatterns(policyData.Config.Flags.Ignore)
-
 	codePatternAssureJSON := []string{
 		"--pcre2",
 		"--no-heading",
@@ -60,25 +52,28 @@ func executeAssure(policy Policy, rgPath string, targetDir string, filesToAssure
 		"-i",
 		"-U",
 		"--json",
-		"-f", searchPatternFile,
 	}
 
 	if targetDir == "" {
 		return fmt.Errorf("no target directory defined")
 	}
 
-	codePatternAssureJSON = append(codePatternAssureJSON, processedIgnorePatterns...)
-
 	matchesFound := true
 
 	// Parallel execution for large file sets
 	if policy.FilePattern == "" {
 		log.Warn().Str("policy", policy.ID).Msg("ASSURE Policy without a filepattern is suboptimal")
 	}
-	if len(filesToAssure) > 25 {
-		matchesFound, err = executeParallelAssure(rgPath, codePatternAssureJSON, filesToAssure, writer)
+	if useNativeEngine() {
+		matchesFound, err = nativeSearch(regexPatternStrings(policy.Regex), filesToAssure, writer)
 	} else {
-		matchesFound, err = executeSingleAssure(rgPath, codePatternAssureJSON, filesToAssure, targetDir, policy, writer)
+		matchesFound, err = searchEachPattern(policy, func(patternFile string, writer *bufio.Writer) (bool, error) {
+			args := slices.Clip(append(slices.Clip(codePatternAssureJSON), "-f", patternFile))
+			if len(filesToAssure) > 25 {
+				return executeParallelAssure(rgPath, args, filesToAssure, writer)
+			}
+			return executeSingleAssure(rgPath, args, filesToAssure, targetDir, policy, writer)
+		}, writer)
 	}
 
 	if err != nil {
@@ -87,6 +82,10 @@ func executeAssure(policy Policy, rgPath string, targetDir string, filesToAssure
 
 	}
 
+	if err := writer.Flush(); err != nil {
+		log.Error().Err(err).Msg("error writing JSON output file")
+	}
+
 	// Patch the JSON output file
 	err = patchJSONOutputFile(jsonOutputFile)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -25,9 +26,15 @@ var (
 
 	rgPath           string
 	gossPath         string
-	policyFile       string
+	policyFiles      []string
 	policyFileSHA256 string
 	policyData       *PolicyFile
+	failOn           string
+	baselineFile     string
+	changedSince     string
+	stagedOnly       bool
+	pushedFiles      []string
+	incrementalAudit bool
 )
 
 var runAuditPerfCmd = &cobra.Command{
@@ -45,34 +52,76 @@ func init() {
 	runAuditPerfCmd.Flags().StringVarP(&environment, "environment", "e", "", "Filter policies that match the specified environment")
 	runAuditPerfCmd.Flags().BoolVar(&envDetection, "env-detection", false, "Enable environment detection if no environment is specified")
 
-	runAuditPerfCmd.Flags().StringVarP(&policyFile, "policy", "p", "", "Policy <FILEPATH> or <URL>")
+	runAuditPerfCmd.Flags().StringArrayVarP(&policyFiles, "policy", "p", nil, "Policy <FILEPATH>, <DIRECTORY> or <URL>, repeat to layer overlays")
 	runAuditPerfCmd.Flags().StringVar(&policyFileSHA256, "checksum", "", "Policy SHA256 expected checksum")
+	runAuditPerfCmd.Flags().StringVar(&exceptionsFile, "exceptions", "", "Exceptions <FILEPATH> with per-finding suppressions")
+	runAuditPerfCmd.Flags().StringVar(&baselineFile, "baseline", "", "Baseline SARIF <FILEPATH>, only new findings drive the exit code and webhooks")
+	runAuditPerfCmd.Flags().StringVar(&changedSince, "changed-since", "", "Only audit target files changed since the git <REF> (committed, uncommitted and untracked)")
+	runAuditPerfCmd.Flags().BoolVar(&stagedOnly, "staged", false, "Only audit target files staged in the git index")
+	runAuditPerfCmd.Flags().StringVar(&scanEngine, "engine", "", "Regex engine for scan and assure policies : native,ripgrep (default ripgrep)")
+	runAuditPerfCmd.Flags().BoolVar(&resultCacheEnabled, "cache", false, "Reuse the results of unchanged files from the result cache")
+	runAuditPerfCmd.Flags().StringVar(&resultCacheDir, "cache-dir", os.Getenv("INTERCEPT_CACHE_DIR"), "Result cache directory, enables the cache (default user cache dir)")
+	runAuditPerfCmd.Flags().StringVar(&failOn, "fail-on", "", "Minimum SARIF level that produces a non-zero exit code : error,warning,never (default warning)")
 }
 
 func runAuditPerf(cmd *cobra.Command, args []string) {
 
-	var err error
-
 	perf := Performance{StartTime: time.Now()}
 
-	sourceType, processedInput, err := DeterminePolicySource(policyFile)
+	config := prepareAudit()
+	defer cleanupSARIFProcessing()
+
+	if failOn == "" {
+		failOn = config.Flags.FailOn
+	}
+	failOnThreshold, err := parseFailOn(failOn)
 	if err != nil {
-		log.Fatal().Err(err)
+		log.Fatal().Err(err).Msg("Invalid fail-on threshold")
 	}
 
-	switch sourceType {
-	case LocalFile:
-		policyData, err = LoadPolicyFile(processedInput)
-	case RemoteURL:
-		policyData, err = LoadRemotePolicy(processedInput, policyFileSHA256)
-	default:
-		log.Fatal().Msg("unknown policy source type")
+	if baselineFile == "" {
+		baselineFile = config.Flags.Baseline
+	}
+	if err := LoadBaseline(baselineFile); err != nil {
+		log.Fatal().Err(err).Str("file", baselineFile).Msg("Error loading baseline")
+	}
+
+	mergedReport, err := executeAudit(config, &perf)
+	if err != nil {
+		log.Error().Err(err).Int("exit-code", ExitCodeCritical).Msg("Audit failed")
+		cleanupSARIFProcessing()
+		os.Exit(ExitCodeCritical)
+	}
+
+	worstLevel := WorstSARIFLevel(mergedReport)
+	exitCode := exitCodeForLevel(worstLevel, failOnThreshold)
+
+	if exitMsg := exitMessageForLevel(config, worstLevel); exitMsg != "" {
+		log.Log().Msg(exitMsg)
 	}
 
-	//policyData, err = LoadPolicyFile(policyFile)
+	log.Info().Msgf("INTERCEPT Run ID: %s", intercept_run_id)
+	log.Info().Msg("Performance Metrics:")
+	log.Info().Msgf("  Start Time: %s", perf.StartTime.Format(time.RFC3339))
+	log.Info().Msgf("  End Time: %s", perf.EndTime.Format(time.RFC3339))
+	log.Info().Msgf("  Execution Time: %d milliseconds", perf.Delta.Milliseconds())
+	log.Info().Str("worst-level", sarifLevelToString(worstLevel)).Int("exit-code", exitCode).Msg("Audit Status")
+
+	if exitCode != ExitCodeClean {
+		cleanupSARIFProcessing()
+		os.Exit(exitCode)
+	}
 
+}
+
+// prepareAudit loads the policy file, resets the output directories and returns the active config
+func prepareAudit() Config {
+
+	var err error
+
+	policyData, err = LoadPolicySources(policyFiles, policyFileSHA256)
 	if err != nil {
-		log.Fatal().Err(err).Str("file", policyFile).Msg("Error loading policy file")
+		log.Fatal().Err(err).Strs("policy", policyFiles).Msg("Error loading policy file")
 	}
 
 	// Clean up output directories
@@ -85,7 +134,6 @@ func runAuditPerf(cmd *cobra.Command, args []string) {
 		log.Fatal().Err(err).Msg("Failed to initialize SARIF processing")
 
 	}
-	defer cleanupSARIFProcessing()
 
 	if err := createOutputDirectories(false); err != nil {
 		log.Fatal().Err(err).Msg("Failed to create output directories")
@@ -99,6 +147,26 @@ func runAuditPerf(cmd *cobra.Command, args []string) {
 
 	config := GetConfig()
 
+	if exceptionsFile == "" {
+		exceptionsFile = config.Flags.ExceptionsFile
+	}
+	if err := resolveScanEngine(config); err != nil {
+		log.Fatal().Err(err).Msg("Invalid engine")
+	}
+	if err := resolveResultCache(config); err != nil {
+		log.Fatal().Err(err).Msg("Error opening result cache")
+	}
+	if err := LoadExceptions(policyData, exceptionsFile); err != nil {
+		log.Fatal().Err(err).Msg("Error loading exceptions")
+	}
+
+	return config
+}
+
+// executeAudit runs all filtered policies against the target and returns the merged SARIF report, a
+// report that can't be merged is an error so that gates fail closed
+func executeAudit(config Config, perf *Performance) (SARIFReport, error) {
+
 	policies_provided := GetPolicies()
 	policies_filtered := filterPolicies(policies_provided, config.Flags.Tags)
 
@@ -107,6 +175,11 @@ func runAuditPerf(cmd *cobra.Command, args []string) {
 	log.Info().Msgf("Total Policies: %d", len(policies_provided))
 	log.Info().Msgf("Total Policies (after filtering): %d", len(policies_filtered))
 
+	if err := validateFileSelections(policies_filtered); err != nil {
+		log.Error().Err(err).Msg("Invalid policy file selection")
+		return SARIFReport{}, err
+	}
+
 	// Check if all policies are API type
 	allAPIorRuntimePolicies := true
 	for _, policy := range policies_filtered {
@@ -127,7 +200,30 @@ func runAuditPerf(cmd *cobra.Command, args []string) {
 		}
 		log.Debug().Msgf("Processing target directory: %s", targetDir)
 
-		allFileInfos, err := CalculateFileHashes(targetDir)
+		// archives and images are audited from a temporary extraction, results keep their virtual paths
+		extractedDir, cleanup, err := resolveArchiveTarget(targetDir)
+		if err != nil {
+			log.Fatal().Err(err).Str("target", targetDir).Msg("Error resolving target")
+		}
+		originalTarget := targetDir
+		targetDir = extractedDir
+		defer func() {
+			cleanup()
+			targetDir = originalTarget
+		}()
+
+		var allFileInfos []FileInfo
+		if stagedOnly {
+			// staged files are listed from the index, they may be deleted or renamed in the working tree
+			var cleanupSnapshot func()
+			allFileInfos, cleanupSnapshot = stagedSnapshot()
+			defer cleanupSnapshot()
+		} else {
+			allFileInfos, err = CalculateFileHashes(targetDir)
+			if err == nil && (changedSince != "" || pushedFiles != nil) {
+				allFileInfos = filterChangedFiles(allFileInfos)
+			}
+		}
 
 		if err != nil {
 			log.Err(err).Msg("Error verifying target")
@@ -144,18 +240,13 @@ func runAuditPerf(cmd *cobra.Command, args []string) {
 
 	commandLine := strings.Join(os.Args, " ")
 
-	_, err = MergeSARIFReports(commandLine, perf, false)
-
+	mergedReport, err := MergeSARIFReports(commandLine, *perf, false)
 	if err != nil {
-		log.Debug().Err(err).Msg("Failed to merge SARIF reports")
+		log.Error().Err(err).Msg("Failed to merge SARIF reports")
+		return mergedReport, fmt.Errorf("failed to merge SARIF reports: %w", err)
 	}
 
-	log.Info().Msgf("INTERCEPT Run ID: %s", intercept_run_id)
-	log.Info().Msg("Performance Metrics:")
-	log.Info().Msgf("  Start Time: %s", perf.StartTime.Format(time.RFC3339))
-	log.Info().Msgf("  End Time: %s", perf.EndTime.Format(time.RFC3339))
-	log.Info().Msgf("  Execution Time: %d milliseconds", perf.Delta.Milliseconds())
-
+	return mergedReport, nil
 }
 
 func filterPolicies(policies []Policy, config_tags []string) []Policy {
@@ -184,6 +275,89 @@ func filterPolicies(policies []Policy, config_tags []string) []Policy {
 	return filtered
 }
 
+// filterChangedFiles restricts the target files to the ones reported by git, API and Runtime policies are not affected
+func filterChangedFiles(allFileInfos []FileInfo) []FileInfo {
+	changedFiles := pushedFiles
+	if changedFiles == nil {
+		var err error
+		changedFiles, err = GitChangedFiles(targetDir, changedSince, false)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error listing changed files from git")
+		}
+	}
+
+	incrementalAudit = true
+	filtered := FilterFilesByList(allFileInfos, changedFiles)
+
+	log.Info().Str("changed-since", changedSince).Msgf("Incremental audit on %d of %d target files", len(filtered), len(allFileInfos))
+
+	return filtered
+}
+
+// stagedSnapshot lists the files staged in the index under the target and audits their index content
+// instead of their working tree content, results keep the paths of the target
+func stagedSnapshot() ([]FileInfo, func()) {
+	if changedSince != "" {
+		log.Fatal().Msg("--changed-since and --staged are mutually exclusive")
+	}
+	stagedFiles, err := GitChangedFiles(targetDir, "", true)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error listing staged files from git")
+	}
+	incrementalAudit = true
+
+	root, err := filepath.Abs(targetDir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error resolving target")
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	ignore, err := newIgnoreMatcher(root, policyData.Config.Flags.Ignore)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error reading ignore rules")
+	}
+
+	// the ignore rules of the working tree apply like in the target walk
+	var paths, rels []string
+	for _, file := range stagedFiles {
+		if resolved, err := filepath.EvalSymlinks(filepath.Dir(file)); err == nil {
+			file = filepath.Join(resolved, filepath.Base(file))
+		}
+		rel, ok := targetRelPath(root, file)
+		if !ok || ignore.matchPathOrParents(rel) {
+			continue
+		}
+		paths = append(paths, file)
+		rels = append(rels, rel)
+	}
+	log.Info().Bool("staged", true).Msgf("Incremental audit on %d staged target files", len(paths))
+	if len(paths) == 0 {
+		return nil, func() {}
+	}
+
+	snapshotDir, cleanup, err := GitIndexSnapshot(targetDir, paths)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error reading staged files from the git index")
+	}
+
+	realTarget := targetDir
+	targetDir = snapshotDir
+	activeArchiveTarget = &archiveTarget{name: filepath.Base(filepath.Clean(realTarget)), root: snapshotDir, realRoot: realTarget}
+
+	snapshotFiles := make([]FileInfo, len(rels))
+	for i, rel := range rels {
+		snapshotFiles[i] = FileInfo{Path: filepath.Join(snapshotDir, filepath.FromSlash(rel))}
+	}
+	hashFileInfos(snapshotFiles)
+
+	return snapshotFiles, func() {
+		activeArchiveTarget = nil
+		targetDir = realTarget
+		cleanup()
+	}
+}
+
 func processPoliciesInParallel(policies []Policy, allFileInfos []FileInfo, rgPath string) {
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, 100) // Limit concurrent goroutines
@@ -203,9 +377,18 @@ func processPoliciesInParallel(policies []Policy, allFileInfos []FileInfo, rgPat
 }
 
 func processPolicy(policy Policy, allFileInfos []FileInfo, rgPath string) {
-	filesToProcess, filePaths := filterFiles(policy, allFileInfos)
+	filesToProcess, filePaths, err := filterFiles(policy, allFileInfos)
+	if err != nil {
+		log.Error().Err(err).Str("policy", policy.ID).Msg("Error selecting files, policy not run")
+		return
+	}
 
-	if policy.Type == "json" || policy.Type == "yaml" || policy.Type == "ini" || policy.Type == "scan" || policy.Type == "assure" {
+	if incrementalAudit && policy.Type != "api" && policy.Type != "runtime" && len(filePaths) == 0 {
+		log.Debug().Str("policy", policy.ID).Msg("No changed files for policy, skipping")
+		return
+	}
+
+	if policy.Type == "json" || policy.Type == "yaml" || policy.Type == "ini" || policy.Type == "scan" || policy.Type == "assure" || policy.Type == "secrets" || policy.Type == "dependencies" || policy.Type == "license" || policy.Type == "dockerfile" || policy.Type == "hcl" {
 
 		log.Debug().Str("policy", policy.ID).Msgf(" Processing files for policy %s ", policy.ID)
 		if len(filesToProcess) < 15 {
@@ -217,45 +400,76 @@ func processPolicy(policy Policy, allFileInfos []FileInfo, rgPath string) {
 		normalizedID := NormalizeFilename(policy.ID)
 		outputPath := fmt.Sprintf("scanned_files_%s.json", normalizedID)
 
-		err := WriteHashesToJSON(filesToProcess, outputPath)
+		err = WriteHashesToJSON(filesToProcess, outputPath)
 		if err != nil {
 			log.Debug().Msgf("Error writing hashes to JSON for policy %s: %v ", policy.ID, err)
 		} else {
 			log.Debug().Msgf("File hashes for policy %s written to: %s ", policy.ID, outputPath)
 		}
 	}
+	if isCacheablePolicy(policy) {
+		processCachedPolicy(policy, rgPath, filesToProcess)
+		return
+	}
 	processPolicyByType(policy, rgPath, gossPath, targetDir, filePaths)
 }
 
-func filterFiles(policy Policy, allFileInfos []FileInfo) ([]FileInfo, []string) {
+func filterFiles(policy Policy, allFileInfos []FileInfo) ([]FileInfo, []string, error) {
 	var filesToProcess []FileInfo
 	var filePaths []string
 
 	if policy.FilePattern != "" {
 		filteredFiles, err := FilterFilesByPattern(allFileInfos, policy.FilePattern)
 		if err != nil {
-			log.Debug().Msgf("Error filtering files for policy %s: %v ", policy.ID, err)
-			return filesToProcess, filePaths
+			return nil, nil, err
 		}
 		filesToProcess = filteredFiles
 	} else {
 		filesToProcess = allFileInfos
 	}
 
+	selection, err := compileFileSelection(policy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid file selection: %w", err)
+	}
+	if selection != nil {
+		filesToProcess = selection.filter(targetDir, filesToProcess)
+	}
+	if policy.Type != "api" && policy.Type != "runtime" {
+		recordSelectedFiles(policy, len(filesToProcess))
+	}
+
 	for _, file := range filesToProcess {
 		filePaths = append(filePaths, file.Path)
 	}
 
-	return filesToProcess, filePaths
+	return filesToProcess, filePaths, nil
 }
 
 func processPolicyByType(policy Policy, rgPath, gossPath, targetDir string, filePaths []string) {
+	if err := runPolicyByType(policy, rgPath, gossPath, targetDir, filePaths); err != nil {
+		log.Debug().Msgf("Error processing %s-type policy %s: %v ", policy.Type, policy.ID, err)
+	}
+}
+
+// runPolicyByType dispatches a policy to its Process*Type function and returns its error
+func runPolicyByType(policy Policy, rgPath, gossPath, targetDir string, filePaths []string) error {
 	var err error
 	switch policy.Type {
 	case "scan":
 		err = ProcessScanType(policy, rgPath, targetDir, filePaths)
 	case "assure":
 		err = ProcessAssureType(policy, rgPath, targetDir, filePaths)
+	case "secrets":
+		err = ProcessSecretsType(policy, targetDir, filePaths)
+	case "dependencies":
+		err = ProcessDependenciesType(policy, targetDir, filePaths)
+	case "license":
+		err = ProcessLicenseType(policy, targetDir, filePaths)
+	case "dockerfile":
+		err = ProcessDockerfileType(policy, targetDir, filePaths)
+	case "hcl":
+		err = ProcessHCLType(policy, targetDir, filePaths)
 	case "runtime":
 		err = ProcessRuntimeType(policy, gossPath, targetDir, filePaths, false)
 	case "api":
@@ -288,12 +502,10 @@ func processPolicyByType(policy Policy, rgPath, gossPath, targetDir string, file
 		err = ProcessRegoType(policy, targetDir, filePaths)
 	default:
 		log.Debug().Msgf("Unsupported policy type %s for policy %s ", policy.Type, policy.ID)
-		return
+		return nil
 	}
 
-	if err != nil {
-		log.Debug().Msgf("Error processing %s-type policy %s: %v ", policy.Type, policy.ID, err)
-	}
+	return err
 }
 
 func GetConfig() Config {
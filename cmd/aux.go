@@ -235,6 +235,13 @@ func convertToJSON(content []byte, contentType string) ([]byte, error) {
 		}
 		return json.Marshal(tomlObj)
 	default:
+		if format, ok := fileFormats[contentType]; ok && format.parse != nil {
+			value, err := format.parse(content)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(value)
+		}
 		return nil, fmt.Errorf("unsupported content type: %s", contentType)
 	}
 }
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// SARIF baselineState values
+const (
+	BaselineStateNew       = "new"
+	BaselineStateUnchanged = "unchanged"
+	BaselineStateAbsent    = "absent"
+)
+
+// fingerprintKey is the key used in SARIF result fingerprints
+const fingerprintKey = "intercept/v1"
+
+var (
+	activeBaseline     []Result
+	baselineOutputFile string
+)
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Write or refresh a baseline SARIF report used by audit --baseline",
+	Long:  `This command runs the same audit as intercept audit and stores the merged SARIF report as a baseline, so later audits only fail on new findings.`,
+	Run:   runBaseline,
+}
+
+func init() {
+	rootCmd.AddCommand(baselineCmd)
+	baselineCmd.Flags().StringVarP(&targetDir, "target", "t", "", "Target directory to audit")
+	baselineCmd.Flags().StringVarP(&tagsAny, "tags-any", "f", "", "Filter policies that match any of the provided tags (comma-separated)")
+	baselineCmd.Flags().StringVar(&tagsAll, "tags-all", "", "Filter policies that match all of the provided tags (comma-separated)")
+	baselineCmd.Flags().StringVarP(&environment, "environment", "e", "", "Filter policies that match the specified environment")
+	baselineCmd.Flags().BoolVar(&envDetection, "env-detection", false, "Enable environment detection if no environment is specified")
+
+	baselineCmd.Flags().StringArrayVarP(&policyFiles, "policy", "p", nil, "Policy <FILEPATH>, <DIRECTORY> or <URL>, repeat to layer overlays")
+	baselineCmd.Flags().StringVar(&policyFileSHA256, "checksum", "", "Policy SHA256 expected checksum")
+	baselineCmd.Flags().StringVar(&scanEngine, "engine", "", "Regex engine for scan and assure policies : native,ripgrep (default ripgrep)")
+	baselineCmd.Flags().BoolVar(&resultCacheEnabled, "cache", false, "Reuse the results of unchanged files from the result cache")
+	baselineCmd.Flags().StringVar(&resultCacheDir, "cache-dir", os.Getenv("INTERCEPT_CACHE_DIR"), "Result cache directory, enables the cache (default user cache dir)")
+	baselineCmd.Flags().StringVar(&exceptionsFile, "exceptions", "", "Exceptions <FILEPATH> with per-finding suppressions")
+	baselineCmd.Flags().StringVar(&baselineOutputFile, "baseline-file", "intercept.baseline.sarif.json", "Baseline SARIF <FILEPATH> to write or refresh")
+}
+
+func runBaseline(cmd *cobra.Command, args []string) {
+
+	perf := Performance{StartTime: time.Now()}
+
+	config := prepareAudit()
+	defer cleanupSARIFProcessing()
+
+	mergedReport, err := executeAudit(config, &perf)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Audit failed, baseline not written")
+	}
+
+	if len(mergedReport.Runs) == 0 {
+		log.Fatal().Msg("No SARIF results produced, baseline not written")
+	}
+
+	if err := writeBaseline(mergedReport, baselineOutputFile); err != nil {
+		log.Fatal().Err(err).Str("file", baselineOutputFile).Msg("Error writing baseline")
+	}
+
+	log.Log().Msgf("Baseline written to %s with %d results", baselineOutputFile, len(mergedReport.Runs[0].Results))
+}
+
+// writeBaseline stores a merged SARIF report as a baseline file
+func writeBaseline(report SARIFReport, filename string) error {
+	for i := range report.Runs {
+		for j := range report.Runs[i].Results {
+			report.Runs[i].Results[j].BaselineState = ""
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal baseline: %w", err)
+	}
+
+	if dir := filepath.Dir(filename); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create baseline directory: %w", err)
+		}
+	}
+
+	return os.WriteFile(filename, data, 0644)
+}
+
+// LoadBaseline reads a previous SARIF report used to classify findings as new, unchanged or absent
+func LoadBaseline(filename string) error {
+	activeBaseline = nil
+	if filename == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("error reading baseline file: %w", err)
+	}
+
+	var report SARIFReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return fmt.Errorf("error parsing baseline file: %w", err)
+	}
+
+	for _, run := range report.Runs {
+		for _, result := range run.Results {
+			if result.BaselineState == BaselineStateAbsent {
+				continue
+			}
+			activeBaseline = append(activeBaseline, result)
+		}
+	}
+
+	log.Debug().Int("results", len(activeBaseline)).Str("file", filename).Msg("Baseline loaded")
+
+	return nil
+}
+
+// fingerprintResult identifies a result independently of its line number: rule ID, normalized URI and snippet hash
+func fingerprintResult(result Result) string {
+	uri := ""
+	content := result.Message.Text
+	if len(result.Locations) > 0 {
+		location := result.Locations[0].PhysicalLocation
+		uri = normalizeResultURI(location.ArtifactLocation.URI)
+		if snippet := location.Region.Snippet.Text; snippet != "" && snippet != "N/A" {
+			content = snippet
+		}
+	}
+	return hashSnippet(strings.Join([]string{result.RuleID, uri, hashSnippet(content)}, "|"))
+}
+
+// normalizeResultURI makes result URIs comparable between runs by making them relative to the target
+func normalizeResultURI(uri string) string {
+	if targetDir != "" && uri != "" && uri != "N/A" {
+		if rel, err := filepath.Rel(targetDir, uri); err == nil && !strings.HasPrefix(rel, "..") {
+			uri = rel
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(uri), "./")
+}
+
+// applyBaseline fingerprints the merged results and sets their SARIF baselineState against the active baseline,
+// findings of the baseline not found anymore are appended as absent
+func applyBaseline(results []Result) []Result {
+	for i := range results {
+		if results[i].Fingerprints == nil {
+			results[i].Fingerprints = map[string]string{}
+		}
+		// redacted results are fingerprinted before their content is replaced
+		if results[i].Fingerprints[fingerprintKey] == "" {
+			results[i].Fingerprints[fingerprintKey] = fingerprintResult(results[i])
+		}
+	}
+
+	if activeBaseline == nil {
+		return results
+	}
+
+	remaining := make(map[string]int)
+	baselineByFingerprint := make(map[string][]Result)
+	for _, result := range activeBaseline {
+		fp := result.Fingerprints[fingerprintKey]
+		if fp == "" {
+			fp = fingerprintResult(result)
+		}
+		remaining[fp]++
+		baselineByFingerprint[fp] = append(baselineByFingerprint[fp], result)
+	}
+
+	for i := range results {
+		fp := results[i].Fingerprints[fingerprintKey]
+		if remaining[fp] > 0 {
+			remaining[fp]--
+			results[i].BaselineState = BaselineStateUnchanged
+		} else {
+			results[i].BaselineState = BaselineStateNew
+		}
+	}
+
+	for fp, count := range remaining {
+		baselineResults := baselineByFingerprint[fp]
+		for j := len(baselineResults) - count; j < len(baselineResults); j++ {
+			absent := baselineResults[j]
+			if absent.Level != SARIFError && absent.Level != SARIFWarning {
+				continue
+			}
+			absent.BaselineState = BaselineStateAbsent
+			absent.Fingerprints = map[string]string{fingerprintKey: fp}
+			results = append(results, absent)
+		}
+	}
+
+	return results
+}
+
+// baselineReport returns a copy of a single policy report with the baseline applied, for reports posted
+// before MergeSARIFReports sets the baselineState of the merged results
+func baselineReport(sarifReport SARIFReport) SARIFReport {
+	report := sarifReport
+	report.Runs = make([]Run, len(sarifReport.Runs))
+	for i, run := range sarifReport.Runs {
+		report.Runs[i] = run
+		report.Runs[i].Results = applyBaseline(append([]Result(nil), run.Results...))
+	}
+	return report
+}
+
+// isBaselineFinding reports whether a result was already known in the baseline and must not drive status or webhooks
+func isBaselineFinding(result Result) bool {
+	return result.BaselineState == BaselineStateUnchanged || result.BaselineState == BaselineStateAbsent
+}
+
+// filterNewFindings returns a copy of the report without results already known in the baseline
+func filterNewFindings(sarifReport SARIFReport) SARIFReport {
+	if activeBaseline == nil {
+		return sarifReport
+	}
+
+	filtered := sarifReport
+	filtered.Runs = make([]Run, len(sarifReport.Runs))
+	for i, run := range sarifReport.Runs {
+		filtered.Runs[i] = run
+		filtered.Runs[i].Results = nil
+		for _, result := range run.Results {
+			if !isBaselineFinding(result) {
+				filtered.Runs[i].Results = append(filtered.Runs[i].Results, result)
+			}
+		}
+	}
+	return filtered
+}
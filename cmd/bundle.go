@@ -0,0 +1,685 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	bundleScheme        = "bundle://"
+	bundleManifestName  = "manifest.json"
+	bundleSignatureName = "manifest.sig"
+	bundlePolicyName    = "policy.yaml"
+	bundleAssetsDir     = "assets"
+	bundleMaxSize       = 64 << 20
+)
+
+var (
+	bundlePublicKey  string
+	bundleUnsigned   bool
+	bundleCacheDir   string
+	bundlePrivateKey string
+	bundleName       string
+	bundleVersion    string
+	bundleOutput     string
+
+	bundleComponentPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// BundleManifest describes the content of a policy bundle, its signature covers the manifest bytes
+type BundleManifest struct {
+	Name             string       `json:"name"`
+	Version          string       `json:"version"`
+	Created          string       `json:"created"`
+	InterceptVersion string       `json:"intercept_version,omitempty"`
+	Policy           string       `json:"policy"`
+	Files            []BundleFile `json:"files"`
+}
+
+type BundleFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Build, verify and install signed policy bundles",
+	Long: `Policy bundles are tar.gz packages holding a policy file, the rego, data and runtime files it references and a signed manifest.
+Installed bundles are kept in a local cache and can be audited offline with --policy bundle://<name>@<version>.`,
+}
+
+var bundleBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Package a policy and its referenced files into a bundle",
+	Example: `  $ intercept bundle build -p policies/rego.yaml --name nginx --version 1.2.0 --key bundle.key
+  $ intercept bundle build -p baseline.yaml -p overlay.yaml --version 2.0.0 --file team.tar.gz`,
+	Run: runBundleBuild,
+}
+
+var bundleVerifyCmd = &cobra.Command{
+	Use:     "verify BUNDLE",
+	Short:   "Verify the signature and content of a bundle",
+	Example: `  $ intercept bundle verify nginx-1.2.0.tar.gz --bundle-key bundle.pub`,
+	Args:    cobra.ExactArgs(1),
+	Run:     runBundleVerify,
+}
+
+var bundleInstallCmd = &cobra.Command{
+	Use:   "install BUNDLE|URL",
+	Short: "Verify a bundle and install it into the local bundle cache",
+	Example: `  $ intercept bundle install https://intercept.cc/bundles/nginx-1.2.0.tar.gz --bundle-key bundle.pub
+  $ intercept audit -p bundle://nginx@1.2.0 -t targets/`,
+	Args: cobra.ExactArgs(1),
+	Run:  runBundleInstall,
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleBuildCmd, bundleVerifyCmd, bundleInstallCmd)
+
+	rootCmd.PersistentFlags().StringVar(&bundlePublicKey, "bundle-key", os.Getenv("INTERCEPT_BUNDLE_KEY"), "Ed25519 public key <FILEPATH> (PEM) that policy bundles must be signed with")
+	rootCmd.PersistentFlags().BoolVar(&bundleUnsigned, "allow-unsigned", false, "Accept policy bundles without verifying their signature when no --bundle-key is set")
+	rootCmd.PersistentFlags().StringVar(&bundleCacheDir, "bundle-cache", os.Getenv("INTERCEPT_BUNDLE_CACHE"), "Policy bundle cache directory (default user cache dir)")
+
+	bundleBuildCmd.Flags().StringArrayVarP(&policyFiles, "policy", "p", nil, "Policy <FILEPATH>, <DIRECTORY> or <URL> to package, repeat to layer overlays")
+	bundleBuildCmd.Flags().StringVar(&bundlePrivateKey, "key", "", "Ed25519 private key <FILEPATH> (PEM, PKCS8) used to sign the bundle")
+	bundleBuildCmd.Flags().StringVar(&bundleName, "name", "", "Bundle name (default policy Namespace)")
+	bundleBuildCmd.Flags().StringVar(&bundleVersion, "version", "", "Bundle version (default policy Version)")
+	bundleBuildCmd.Flags().StringVar(&bundleOutput, "file", "", "Bundle <FILEPATH> to write (default <name>-<version>.tar.gz)")
+	bundleInstallCmd.Flags().StringVar(&policyFileSHA256, "checksum", "", "Bundle SHA256 expected checksum")
+}
+
+func runBundleBuild(cmd *cobra.Command, args []string) {
+	if len(policyFiles) == 0 {
+		log.Fatal().Msg("A policy is required to build a bundle")
+	}
+
+	policy, err := composePolicySources(policyFiles, policyFileSHA256)
+	if err != nil {
+		log.Fatal().Err(err).Strs("policy", policyFiles).Msg("Error loading policy file")
+	}
+
+	name := bundleName
+	if name == "" {
+		name = NormalizeFilename(policy.Namespace)
+	}
+	version := bundleVersion
+	if version == "" {
+		version = policy.Version
+	}
+	if err := validateBundleComponent("name", name); err != nil {
+		log.Fatal().Err(err).Msg("Use --name to set the bundle name")
+	}
+	if err := validateBundleComponent("version", version); err != nil {
+		log.Fatal().Err(err).Msg("Use --version to set the bundle version")
+	}
+
+	var key ed25519.PrivateKey
+	if bundlePrivateKey != "" {
+		if key, err = loadBundlePrivateKey(bundlePrivateKey); err != nil {
+			log.Fatal().Err(err).Str("key", bundlePrivateKey).Msg("Error loading bundle signing key")
+		}
+	} else {
+		log.Warn().Msg("No --key provided, the bundle is not signed")
+	}
+
+	files, err := collectBundleFiles(policy)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error collecting bundle files")
+	}
+
+	manifest := BundleManifest{
+		Name:             name,
+		Version:          version,
+		Created:          time.Now().UTC().Format(time.RFC3339),
+		InterceptVersion: buildVersion,
+		Policy:           bundlePolicyName,
+	}
+	archive, err := writeBundleArchive(manifest, files, key)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error writing bundle")
+	}
+
+	output := bundleOutput
+	if output == "" {
+		output = fmt.Sprintf("%s-%s.tar.gz", name, version)
+	}
+	if err := os.WriteFile(output, archive, 0644); err != nil {
+		log.Fatal().Err(err).Str("file", output).Msg("Error writing bundle")
+	}
+
+	log.Log().Msgf("Bundle %s@%s written to %s (%d files, signed: %t)", name, version, output, len(files)+1, key != nil)
+}
+
+func runBundleVerify(cmd *cobra.Command, args []string) {
+	if bundlePublicKey == "" {
+		log.Fatal().Msg("A public key is required, use --bundle-key or INTERCEPT_BUNDLE_KEY")
+	}
+
+	data, err := readBundleSource(args[0])
+	if err != nil {
+		log.Fatal().Err(err).Str("bundle", args[0]).Msg("Error reading bundle")
+	}
+	files, err := readBundleArchive(data)
+	if err != nil {
+		log.Fatal().Err(err).Str("bundle", args[0]).Msg("Error reading bundle")
+	}
+	manifest, err := verifyBundleFiles(files)
+	if err != nil {
+		log.Fatal().Err(err).Str("bundle", args[0]).Msg("Bundle verification failed")
+	}
+
+	log.Log().Msgf("Bundle %s@%s verified (%d files, created %s)", manifest.Name, manifest.Version, len(manifest.Files), manifest.Created)
+	for _, file := range manifest.Files {
+		log.Log().Msgf("  %s  %s", file.SHA256, file.Path)
+	}
+}
+
+func runBundleInstall(cmd *cobra.Command, args []string) {
+	data, err := readBundleSource(args[0])
+	if err != nil {
+		log.Fatal().Err(err).Str("bundle", args[0]).Msg("Error reading bundle")
+	}
+	dir, err := installBundleData(args[0], data)
+	if err != nil {
+		log.Fatal().Err(err).Str("bundle", args[0]).Msg("Error installing bundle")
+	}
+
+	manifest, err := readBundleManifest(dir)
+	if err != nil {
+		log.Fatal().Err(err).Str("dir", dir).Msg("Error reading installed bundle")
+	}
+	log.Log().Msgf("Installed bundle %s@%s to %s, audit it with --policy %s%s@%s", manifest.Name, manifest.Version, dir, bundleScheme, manifest.Name, manifest.Version)
+}
+
+// collectBundleFiles renders the policy file and copies every referenced asset under assets/, rewriting the paths
+func collectBundleFiles(policy *PolicyFile) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	assets := make(map[string]string)
+
+	for i := range policy.Policies {
+		for _, asset := range policyAssetPaths(&policy.Policies[i]) {
+			if *asset == "" {
+				continue
+			}
+			source, err := filepath.Abs(*asset)
+			if err != nil {
+				return nil, err
+			}
+			if bundlePath, ok := assets[source]; ok {
+				*asset = bundlePath
+				continue
+			}
+
+			content, err := os.ReadFile(source)
+			if err != nil {
+				return nil, fmt.Errorf("policy %s references %s: %w", policy.Policies[i].ID, *asset, err)
+			}
+			bundlePath := path.Join(bundleAssetsDir, filepath.Base(source))
+			for n := 1; files[bundlePath] != nil; n++ {
+				bundlePath = path.Join(bundleAssetsDir, fmt.Sprintf("%d-%s", n, filepath.Base(source)))
+			}
+			files[bundlePath] = content
+			assets[source] = bundlePath
+			*asset = bundlePath
+		}
+	}
+
+	content, err := yaml.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	files[bundlePolicyName] = content
+
+	return files, nil
+}
+
+// writeBundleArchive writes a reproducible tar.gz with the manifest first, the signature second and the files sorted
+func writeBundleArchive(manifest BundleManifest, files map[string][]byte, key ed25519.PrivateKey) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	manifest.Files = make([]BundleFile, 0, len(names))
+	for _, name := range names {
+		sum := sha256.Sum256(files[name])
+		manifest.Files = append(manifest.Files, BundleFile{Path: name, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(files[name]))})
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	type bundleEntry struct {
+		name    string
+		content []byte
+	}
+	entries := []bundleEntry{{bundleManifestName, manifestData}}
+	if key != nil {
+		signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, manifestData))
+		entries = append(entries, bundleEntry{bundleSignatureName, []byte(signature + "\n")})
+	}
+	for _, name := range names {
+		entries = append(entries, bundleEntry{name, files[name]})
+	}
+
+	modTime, _ := time.Parse(time.RFC3339, manifest.Created)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content)), ModTime: modTime, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(entry.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readBundleArchive reads the regular files of a bundle, rejecting paths that escape the bundle root
+func readBundleArchive(data []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("not a gzip archive: %w", err)
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	var total int64
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, fmt.Errorf("unsupported entry type for %s", header.Name)
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid path %s in bundle", header.Name)
+		}
+		total += header.Size
+		if total > bundleMaxSize {
+			return nil, fmt.Errorf("bundle exceeds %d bytes", bundleMaxSize)
+		}
+		content, err := io.ReadAll(io.LimitReader(tr, header.Size))
+		if err != nil {
+			return nil, err
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+// verifyBundleFiles checks the manifest signature against the configured public key and every file checksum.
+// Without a configured key the bundle is rejected, unless --allow-unsigned skips the signature check.
+func verifyBundleFiles(files map[string][]byte) (*BundleManifest, error) {
+	manifestData, ok := files[bundleManifestName]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no %s", bundleManifestName)
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", bundleManifestName, err)
+	}
+	if err := validateBundleComponent("name", manifest.Name); err != nil {
+		return nil, err
+	}
+	if err := validateBundleComponent("version", manifest.Version); err != nil {
+		return nil, err
+	}
+
+	if bundlePublicKey != "" {
+		key, err := loadBundlePublicKey(bundlePublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading bundle public key: %w", err)
+		}
+		signatureData, ok := files[bundleSignatureName]
+		if !ok {
+			return nil, fmt.Errorf("bundle %s@%s is not signed", manifest.Name, manifest.Version)
+		}
+		signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signatureData)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", bundleSignatureName, err)
+		}
+		if !ed25519.Verify(key, manifestData, signature) {
+			return nil, fmt.Errorf("signature of bundle %s@%s does not match the public key", manifest.Name, manifest.Version)
+		}
+	} else if bundleUnsigned {
+		log.Warn().Str("bundle", manifest.Name+"@"+manifest.Version).Msg("No bundle public key configured, signature not verified (--allow-unsigned)")
+	} else {
+		return nil, fmt.Errorf("no bundle public key configured to verify %s@%s, use --bundle-key or --allow-unsigned", manifest.Name, manifest.Version)
+	}
+
+	listed := map[string]bool{bundleManifestName: true, bundleSignatureName: true}
+	for _, file := range manifest.Files {
+		content, ok := files[file.Path]
+		if !ok {
+			return nil, fmt.Errorf("file %s listed in the manifest is missing", file.Path)
+		}
+		sum := sha256.Sum256(content)
+		if hex.EncodeToString(sum[:]) != file.SHA256 {
+			return nil, fmt.Errorf("checksum mismatch for %s", file.Path)
+		}
+		listed[file.Path] = true
+	}
+	for name := range files {
+		if !listed[name] {
+			return nil, fmt.Errorf("file %s is not listed in the manifest", name)
+		}
+	}
+	if _, ok := files[manifest.Policy]; !ok {
+		return nil, fmt.Errorf("policy file %s is missing", manifest.Policy)
+	}
+
+	return &manifest, nil
+}
+
+// installBundleData verifies a bundle archive and extracts it to <cache>/<name>/<version>, replacing a previous install
+func installBundleData(source string, data []byte) (string, error) {
+	files, err := readBundleArchive(data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", source, err)
+	}
+	manifest, err := verifyBundleFiles(files)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", source, err)
+	}
+
+	cache, err := bundleCachePath()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cache, manifest.Name, manifest.Version)
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", err
+	}
+
+	staging, err := os.MkdirTemp(filepath.Dir(dir), "."+manifest.Version+"-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(staging)
+
+	for name, content := range files {
+		target := filepath.Join(staging, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		return "", err
+	}
+
+	log.Debug().Str("bundle", manifest.Name+"@"+manifest.Version).Str("dir", dir).Msg("Bundle installed")
+	return dir, nil
+}
+
+// readBundleDir reads an installed bundle back from the cache so it can be verified again before use
+func readBundleDir(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	return files, err
+}
+
+func readBundleManifest(dir string) (*BundleManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, bundleManifestName))
+	if err != nil {
+		return nil, err
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// resolveCachedBundle finds <name>@<version> in the cache, without a version the highest installed one is used
+func resolveCachedBundle(reference string) (string, error) {
+	cache, err := bundleCachePath()
+	if err != nil {
+		return "", err
+	}
+
+	name, version, _ := strings.Cut(reference, "@")
+	if err := validateBundleComponent("name", name); err != nil {
+		return "", err
+	}
+	if version == "" {
+		entries, err := os.ReadDir(filepath.Join(cache, name))
+		if err != nil {
+			return "", fmt.Errorf("bundle %s is not installed", name)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && (version == "" || compareBundleVersions(entry.Name(), version) > 0) {
+				version = entry.Name()
+			}
+		}
+		if version == "" {
+			return "", fmt.Errorf("bundle %s is not installed", name)
+		}
+	} else if err := validateBundleComponent("version", version); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(cache, name, version)
+	if _, err := os.Stat(filepath.Join(dir, bundleManifestName)); err != nil {
+		return "", fmt.Errorf("bundle %s@%s is not installed, run intercept bundle install first", name, version)
+	}
+	return dir, nil
+}
+
+func (c *policyComposer) addBundleArchive(archive string, topLevel bool) error {
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		return err
+	}
+	if topLevel && c.checksum != "" {
+		if actual, err := calculateSHA256(archive); err != nil || actual != c.checksum {
+			return fmt.Errorf("policy checksum mismatch for %s: expected %s, got %s", archive, c.checksum, actual)
+		}
+	}
+	dir, err := installBundleData(archive, data)
+	if err != nil {
+		return err
+	}
+	return c.addBundleDir(dir)
+}
+
+func (c *policyComposer) addCachedBundle(reference string) error {
+	dir, err := resolveCachedBundle(reference)
+	if err != nil {
+		return err
+	}
+	return c.addBundleDir(dir)
+}
+
+// addBundleDir verifies an installed bundle again, the cache may be shared or copied to offline hosts
+func (c *policyComposer) addBundleDir(dir string) error {
+	files, err := readBundleDir(dir)
+	if err != nil {
+		return err
+	}
+	manifest, err := verifyBundleFiles(files)
+	if err != nil {
+		return fmt.Errorf("%s: %w", dir, err)
+	}
+	return c.addPolicyFile(filepath.Join(dir, filepath.FromSlash(manifest.Policy)), true)
+}
+
+func readBundleSource(source string) ([]byte, error) {
+	if isURL(source) {
+		data, err := downloadPolicySource(source)
+		if err != nil {
+			return nil, err
+		}
+		if policyFileSHA256 != "" {
+			sum := sha256.Sum256(data)
+			if actual := hex.EncodeToString(sum[:]); actual != policyFileSHA256 {
+				return nil, fmt.Errorf("bundle checksum mismatch: expected %s, got %s", policyFileSHA256, actual)
+			}
+		}
+		return data, nil
+	}
+	return os.ReadFile(source)
+}
+
+func isBundleArchive(source string) bool {
+	// remote bundles may carry a query string, e.g. a signed download URL
+	if isURL(source) {
+		source, _, _ = strings.Cut(source, "?")
+	}
+	return strings.HasSuffix(source, ".tar.gz") || strings.HasSuffix(source, ".tgz")
+}
+
+func bundleCachePath() (string, error) {
+	if bundleCacheDir != "" {
+		return bundleCacheDir, nil
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("no bundle cache directory, use --bundle-cache: %w", err)
+	}
+	return filepath.Join(cache, "intercept", "bundles"), nil
+}
+
+func validateBundleComponent(field, value string) error {
+	if !bundleComponentPattern.MatchString(value) {
+		return fmt.Errorf("invalid bundle %s %q", field, value)
+	}
+	return nil
+}
+
+// compareBundleVersions compares dotted versions numerically where possible, "1.10.0" > "1.9.2"
+func compareBundleVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case xerr == nil && yerr == nil && xn != yn:
+			if xn > yn {
+				return 1
+			}
+			return -1
+		case (xerr != nil || yerr != nil) && x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
+
+func loadBundlePublicKey(filename string) (ed25519.PublicKey, error) {
+	block, err := readPEMBlock(filename)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 public key", filename)
+	}
+	return publicKey, nil
+}
+
+func loadBundlePrivateKey(filename string) (ed25519.PrivateKey, error) {
+	block, err := readPEMBlock(filename)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 private key", filename)
+	}
+	return privateKey, nil
+}
+
+func readPEMBlock(filename string) (*pem.Block, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not PEM encoded", filename)
+	}
+	return block, nil
+}
@@ -0,0 +1,464 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyOverride changes a policy inherited from an included file or an earlier --policy source
+type PolicyOverride struct {
+	ID          string        `yaml:"id"`
+	Disabled    bool          `yaml:"disabled,omitempty"`
+	Enforcement []Enforcement `yaml:"enforcement,omitempty"`
+}
+
+// policyLayer is one policy file as read from disk or a remote endpoint, before merging
+type policyLayer struct {
+	source string
+	file   PolicyFile
+}
+
+type policyComposer struct {
+	checksum string
+	layers   []policyLayer
+	loaded   map[string]bool
+	stack    []string
+}
+
+// LoadPolicySources loads every policy file, URL or directory in order, resolves their includes
+// and merges them into a single PolicyFile. Later sources override earlier ones.
+func LoadPolicySources(inputs []string, expectedChecksum string) (*PolicyFile, error) {
+	policyFile, err := composePolicySources(inputs, expectedChecksum)
+	if err != nil {
+		return nil, err
+	}
+	finalizePolicyFile(policyFile)
+
+	return policyFile, nil
+}
+
+// composePolicySources merges the sources without normalizing IDs, so the result can be written back as YAML
+func composePolicySources(inputs []string, expectedChecksum string) (*PolicyFile, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no policy source provided")
+	}
+
+	c := &policyComposer{checksum: expectedChecksum, loaded: make(map[string]bool)}
+	for _, input := range inputs {
+		if err := c.addSource(input, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return mergePolicyLayers(c.layers)
+}
+
+// addSource loads a single --policy value, topLevel sources are checked against the expected checksum
+func (c *policyComposer) addSource(input string, topLevel bool) error {
+	if strings.HasPrefix(input, bundleScheme) {
+		return c.addCachedBundle(strings.TrimPrefix(input, bundleScheme))
+	}
+
+	sourceType, path, err := DeterminePolicySource(input)
+	if err != nil {
+		return err
+	}
+	if sourceType == RemoteURL {
+		expected := ""
+		if topLevel {
+			expected = c.checksum
+		}
+		return c.addURL(path, expected)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if isBundleArchive(path) {
+			return c.addBundleArchive(path, topLevel)
+		}
+		return c.addFile(path)
+	}
+
+	files, err := policyFilesInDir(path)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no policy files found in directory %s", path)
+	}
+	for _, file := range files {
+		if err := c.addFile(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *policyComposer) addFile(path string) error {
+	return c.addPolicyFile(path, false)
+}
+
+// addPolicyFile loads a local policy file, bundled files only resolve their assets inside the bundle
+func (c *policyComposer) addPolicyFile(path string, bundled bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return c.addLayer(path, data, func(include string) (string, error) {
+		if filepath.IsAbs(include) || strings.HasPrefix(include, bundleScheme) || isRemoteInclude(include) {
+			return include, nil
+		}
+		return filepath.Join(filepath.Dir(path), include), nil
+	}, func(layer *PolicyFile) {
+		resolvePolicyAssets(layer, filepath.Dir(path), bundled)
+	})
+}
+
+// resolvePolicyAssets makes relative rego and runtime paths absolute when they only exist next to the policy file,
+// paths valid from the working directory are kept as written unless the file comes from a bundle
+func resolvePolicyAssets(layer *PolicyFile, dir string, bundled bool) {
+	for i := range layer.Policies {
+		for _, asset := range policyAssetPaths(&layer.Policies[i]) {
+			if *asset == "" || filepath.IsAbs(*asset) {
+				continue
+			}
+			if bundled {
+				*asset = filepath.Join(dir, filepath.FromSlash(*asset))
+				continue
+			}
+			if _, err := os.Stat(*asset); err == nil {
+				continue
+			}
+			candidate := filepath.Join(dir, *asset)
+			if _, err := os.Stat(candidate); err == nil {
+				*asset = candidate
+			}
+		}
+	}
+}
+
+// policyAssetPaths returns the fields of a policy that reference files outside the policy YAML
+func policyAssetPaths(policy *Policy) []*string {
+	return []*string{&policy.Rego.PolicyFile, &policy.Rego.PolicyData, &policy.Runtime.Config, &policy.Dependencies.Advisories}
+}
+
+// addURL downloads a remote policy source and checks it against the expected SHA256 when one is given
+func (c *policyComposer) addURL(source string, expected string) error {
+	data, err := downloadPolicySource(source)
+	if err != nil {
+		return err
+	}
+	if expected != "" {
+		sum := sha256.Sum256(data)
+		if actual := hex.EncodeToString(sum[:]); actual != expected {
+			return fmt.Errorf("policy checksum mismatch for %s: expected %s, got %s", source, expected, actual)
+		}
+	}
+
+	if isBundleArchive(source) {
+		dir, err := installBundleData(source, data)
+		if err != nil {
+			return err
+		}
+		return c.addBundleDir(dir)
+	}
+
+	base, err := url.Parse(source)
+	if err != nil {
+		return err
+	}
+	return c.addLayer(source, data, func(include string) (string, error) {
+		ref, err := url.Parse(include)
+		if err != nil {
+			return "", err
+		}
+		if ref.Scheme == "bundle" {
+			return include, nil
+		}
+		return base.ResolveReference(ref).String(), nil
+	}, nil)
+}
+
+func downloadPolicySource(source string) ([]byte, error) {
+	resp, err := resty.New().R().Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download policy file: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("failed to download policy file %s: HTTP status %d", source, resp.StatusCode())
+	}
+	return resp.Body(), nil
+}
+
+// addLayer parses a policy file and loads its includes first, so the including file overrides them
+func (c *policyComposer) addLayer(source string, data []byte, resolve func(string) (string, error), prepare func(*PolicyFile)) error {
+	for i, s := range c.stack {
+		if s == source {
+			return fmt.Errorf("include cycle detected: %s", strings.Join(append(c.stack[i:], source), " -> "))
+		}
+	}
+	// a file reached through several includes is only loaded once, at its first position
+	if c.loaded[source] {
+		return nil
+	}
+
+	var layer PolicyFile
+	if err := yaml.Unmarshal(data, &layer); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	if prepare != nil {
+		prepare(&layer)
+	}
+
+	c.stack = append(c.stack, source)
+	for _, include := range layer.Include {
+		resolved, err := resolve(include)
+		if err != nil {
+			return fmt.Errorf("%s: invalid include %q: %w", source, include, err)
+		}
+		if err := c.addInclude(resolved); err != nil {
+			return fmt.Errorf("%s: include %q: %w", source, include, err)
+		}
+	}
+	c.stack = c.stack[:len(c.stack)-1]
+
+	c.loaded[source] = true
+	c.layers = append(c.layers, policyLayer{source: source, file: layer})
+
+	log.Debug().Str("source", source).Int("policies", len(layer.Policies)).Int("overrides", len(layer.Overrides)).Msg("Loaded policy layer")
+
+	return nil
+}
+
+// addInclude loads an include target, local includes may be glob patterns
+func (c *policyComposer) addInclude(include string) error {
+	if isRemoteInclude(include) {
+		source, expected, err := c.pinnedInclude(include)
+		if err != nil {
+			return err
+		}
+		return c.addURL(source, expected)
+	}
+	if strings.HasPrefix(include, bundleScheme) || !strings.ContainsAny(include, "*?[") {
+		return c.addSource(include, false)
+	}
+
+	matches, err := filepath.Glob(include)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("no policy files match %s", include)
+	}
+	sort.Strings(matches)
+	for _, match := range matches {
+		if err := c.addSource(match, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isRemoteInclude(include string) bool {
+	return strings.HasPrefix(include, "http://") || strings.HasPrefix(include, "https://")
+}
+
+// pinnedInclude splits the #sha256=<digest> pin off a remote include. Remote includes must use https,
+// and must be pinned when --checksum is set so the checksum covers every file it pulls in.
+func (c *policyComposer) pinnedInclude(include string) (string, string, error) {
+	ref, err := url.Parse(include)
+	if err != nil {
+		return "", "", err
+	}
+	if ref.Scheme != "https" {
+		return "", "", fmt.Errorf("remote include %s must use https", include)
+	}
+
+	expected := ""
+	if ref.Fragment != "" {
+		digest, ok := strings.CutPrefix(ref.Fragment, "sha256=")
+		if !ok || len(digest) != sha256.Size*2 {
+			return "", "", fmt.Errorf("remote include %s: pin must be #sha256=<digest>", include)
+		}
+		expected = strings.ToLower(digest)
+		ref.Fragment = ""
+	}
+	if expected == "" && c.checksum != "" {
+		return "", "", fmt.Errorf("remote include %s must be pinned with #sha256=<digest> when --checksum is set", include)
+	}
+
+	return ref.String(), expected, nil
+}
+
+// policyFilesInDir lists the YAML files of a policy directory in lexical order
+func policyFilesInDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml":
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// mergePolicyLayers combines layers in load order. Config scalars take the last non-empty value,
+// lists are appended without duplicates and hooks are merged by name. Policy IDs must be unique
+// across layers, an overlay changes an inherited policy through Overrides instead.
+func mergePolicyLayers(layers []policyLayer) (*PolicyFile, error) {
+	merged := &PolicyFile{}
+	owners := make(map[string]string)
+
+	for _, layer := range layers {
+		mergeConfigValue(reflect.ValueOf(&merged.Config).Elem(), reflect.ValueOf(layer.file.Config))
+		if layer.file.Version != "" {
+			merged.Version = layer.file.Version
+		}
+		if layer.file.Namespace != "" {
+			merged.Namespace = layer.file.Namespace
+		}
+
+		for _, override := range layer.file.Overrides {
+			if err := applyPolicyOverride(merged, override, owners); err != nil {
+				return nil, fmt.Errorf("%s: %w", layer.source, err)
+			}
+		}
+
+		for _, policy := range layer.file.Policies {
+			id := NormalizePolicyName(policy.ID)
+			if owner, ok := owners[id]; ok && owner != layer.source {
+				return nil, fmt.Errorf("policy ID %s defined in both %s and %s, use Overrides to change an inherited policy", id, owner, layer.source)
+			}
+			owners[id] = layer.source
+			merged.Policies = append(merged.Policies, policy)
+		}
+	}
+
+	return merged, nil
+}
+
+func applyPolicyOverride(merged *PolicyFile, override PolicyOverride, owners map[string]string) error {
+	id := NormalizePolicyName(override.ID)
+	if id == "" {
+		return fmt.Errorf("override without id")
+	}
+	if _, ok := owners[id]; !ok {
+		return fmt.Errorf("override %s matches no inherited policy", id)
+	}
+
+	policies := merged.Policies[:0]
+	for _, policy := range merged.Policies {
+		if NormalizePolicyName(policy.ID) == id {
+			if override.Disabled {
+				// the ID is free again, a later layer may define a replacement
+				delete(owners, id)
+				log.Debug().Str("policy", id).Msg("Policy disabled by override")
+				continue
+			}
+			if len(override.Enforcement) > 0 {
+				policy.Enforcement = override.Enforcement
+			}
+		}
+		policies = append(policies, policy)
+	}
+	merged.Policies = policies
+
+	return nil
+}
+
+func mergeConfigValue(dst, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Struct:
+		for i := 0; i < dst.NumField(); i++ {
+			mergeConfigValue(dst.Field(i), src.Field(i))
+		}
+	case reflect.String:
+		if src.String() != "" {
+			dst.Set(src)
+		}
+	case reflect.Slice:
+		if src.Len() == 0 {
+			return
+		}
+		if hooks, ok := src.Interface().([]HookConfig); ok {
+			dst.Set(reflect.ValueOf(mergeHooks(dst.Interface().([]HookConfig), hooks)))
+			return
+		}
+		if values, ok := src.Interface().([]string); ok {
+			existing, _ := dst.Interface().([]string)
+			dst.Set(reflect.ValueOf(appendUnique(existing, values...)))
+			return
+		}
+		dst.Set(src)
+	}
+}
+
+// mergeHooks replaces hooks with the same name in place and appends new ones
+func mergeHooks(hooks, overlay []HookConfig) []HookConfig {
+	merged := append([]HookConfig(nil), hooks...)
+	for _, hook := range overlay {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == hook.Name {
+				merged[i] = hook
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, hook)
+		}
+	}
+	return merged
+}
+
+func appendUnique(list []string, values ...string) []string {
+	result := append([]string(nil), list...)
+	for _, value := range values {
+		if !containsString(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// expandPolicyPaths replaces directories with the policy files they contain, used by commands that work per file
+func expandPolicyPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		dirFiles, err := policyFilesInDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, dirFiles...)
+	}
+	return files, nil
+}
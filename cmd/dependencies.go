@@ -0,0 +1,354 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dependencyAdvisory is an entry of the local advisory file of a dependencies policy,
+// affected is a version constraint, severity is critical, high, moderate, low or a SARIF level
+type dependencyAdvisory struct {
+	ID        string   `yaml:"id"`
+	Ecosystem string   `yaml:"ecosystem"`
+	Name      string   `yaml:"name"`
+	Affected  string   `yaml:"affected"`
+	Fixed     string   `yaml:"fixed,omitempty"`
+	Severity  string   `yaml:"severity,omitempty"`
+	Summary   string   `yaml:"summary,omitempty"`
+	Aliases   []string `yaml:"aliases,omitempty"`
+}
+
+// dependencyMatcher is a compiled deny, constraint or advisory rule
+type dependencyMatcher struct {
+	rule       RegexPattern
+	ecosystem  string
+	name       *regexp.Regexp
+	constraint versionConstraint
+}
+
+type dependencyFinding struct {
+	pkg     dependencyPackage
+	reason  string
+	pattern *RegexPattern
+}
+
+// ProcessDependenciesType handles policies of type "dependencies", manifests and lockfiles are parsed offline
+func ProcessDependenciesType(policy Policy, targetDir string, filePaths []string) error {
+	if policy.Type != "dependencies" {
+		return nil
+	}
+
+	findings, err := executeDependencies(policy, filePaths)
+	if err != nil {
+		log.Error().Err(err).Msgf("error checking dependencies for policy %s", policy.ID)
+		return fmt.Errorf("error checking dependencies for policy %s: %w", policy.ID, err)
+	}
+
+	sarifReport := GenerateDependenciesSARIFReport(policy, findings)
+
+	reportID := policy.ID
+	if policy.RunID != "" {
+		reportID = policy.RunID
+	}
+	if err := writeSARIFReport(reportID, sarifReport); err != nil {
+		log.Error().Err(err).Msg("error writing SARIF report")
+		return fmt.Errorf("error writing SARIF report: %w", err)
+	}
+
+	log.Debug().Msgf("Policy %s processed. %d dependency findings", policy.ID, len(findings))
+	return nil
+}
+
+func executeDependencies(policy Policy, filePaths []string) ([]dependencyFinding, error) {
+	config := policy.Dependencies
+
+	deny, err := compileDependencyRules(config.Deny, "deny")
+	if err != nil {
+		return nil, err
+	}
+	constraints, err := compileDependencyRules(config.Constraints, "constraint")
+	if err != nil {
+		return nil, err
+	}
+	advisories, err := loadDependencyAdvisories(config.Advisories)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []dependencyFinding
+	for _, file := range filePaths {
+		packages, err := parseDependencyManifest(file)
+		if err != nil {
+			log.Warn().Err(err).Str("policy", policy.ID).Msg("skipping unreadable manifest")
+			continue
+		}
+		sortDependencyPackages(packages)
+
+		for _, pkg := range packages {
+			if len(config.Ecosystems) > 0 && !containsFold(config.Ecosystems, pkg.Ecosystem) {
+				continue
+			}
+			if config.DirectOnly && !pkg.Direct {
+				continue
+			}
+			findings = append(findings, evaluateDependency(config, pkg, deny, constraints, advisories)...)
+		}
+	}
+	return findings, nil
+}
+
+// evaluateDependency applies the deny list, the version constraints, the license lists and the advisories to a package
+func evaluateDependency(config DependenciesConfig, pkg dependencyPackage, deny, constraints, advisories []dependencyMatcher) []dependencyFinding {
+	var findings []dependencyFinding
+
+	for i := range deny {
+		matcher := &deny[i]
+		if !matcher.selects(pkg) {
+			continue
+		}
+		// a version constraint on a range only declaration can't be decided
+		if matcher.constraint != nil && (pkg.Version == "" || !matcher.constraint.matches(pkg.Version)) {
+			continue
+		}
+		findings = append(findings, dependencyFinding{pkg: pkg, reason: "denied package", pattern: &matcher.rule})
+	}
+
+	for i := range constraints {
+		matcher := &constraints[i]
+		if !matcher.selects(pkg) || matcher.constraint == nil || pkg.Version == "" || matcher.constraint.matches(pkg.Version) {
+			continue
+		}
+		findings = append(findings, dependencyFinding{pkg: pkg, reason: fmt.Sprintf("version does not satisfy %s", matcher.rule.Pattern), pattern: &matcher.rule})
+	}
+
+	if pkg.License == "" {
+		if severity, ok := unknownDependencyLicense(config.Licenses); ok {
+			findings = append(findings, dependencyFinding{pkg: pkg, reason: "license unknown", pattern: &RegexPattern{
+				ID:       "license",
+				Severity: severity,
+			}})
+		}
+	} else if reason := checkDependencyLicense(config.Licenses, pkg.License); reason != "" {
+		findings = append(findings, dependencyFinding{pkg: pkg, reason: reason, pattern: &RegexPattern{
+			ID:       "license",
+			Severity: config.Licenses.Severity,
+		}})
+	}
+
+	for i := range advisories {
+		matcher := &advisories[i]
+		if !matcher.selects(pkg) || pkg.Version == "" || !matcher.constraint.matches(pkg.Version) {
+			continue
+		}
+		findings = append(findings, dependencyFinding{pkg: pkg, reason: "vulnerable version", pattern: &matcher.rule})
+	}
+
+	return findings
+}
+
+func (m *dependencyMatcher) selects(pkg dependencyPackage) bool {
+	if m.ecosystem != "" && !strings.EqualFold(m.ecosystem, pkg.Ecosystem) {
+		return false
+	}
+	return m.name.MatchString(pkg.Name)
+}
+
+func compileDependencyRules(rules []DependencyRule, kind string) ([]dependencyMatcher, error) {
+	var matchers []dependencyMatcher
+	for i, rule := range rules {
+		name, err := compileDependencyName(rule.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", kind, i, err)
+		}
+		matcher := dependencyMatcher{
+			rule: RegexPattern{
+				ID:          rule.ID,
+				Pattern:     rule.Version,
+				Description: rule.Description,
+				Severity:    rule.Severity,
+				MsgSolution: rule.MsgSolution,
+			},
+			ecosystem: rule.Ecosystem,
+			name:      name,
+		}
+		if rule.Version != "" {
+			if matcher.constraint, err = parseVersionConstraint(rule.Version); err != nil {
+				return nil, fmt.Errorf("%s[%d]: %w", kind, i, err)
+			}
+		} else if kind == "constraint" {
+			return nil, fmt.Errorf("%s[%d]: constraints require a version", kind, i)
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+// compileDependencyName matches package names case-insensitively, * matches any characters
+func compileDependencyName(name string) (*regexp.Regexp, error) {
+	if name == "" {
+		return nil, fmt.Errorf("rule without name")
+	}
+	parts := strings.Split(name, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.Compile("(?i)^" + strings.Join(parts, ".*") + "$")
+}
+
+// loadDependencyAdvisories reads the local advisory file (YAML or JSON list), no network access is involved
+func loadDependencyAdvisories(path string) ([]dependencyMatcher, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading advisories: %w", err)
+	}
+	var advisories []dependencyAdvisory
+	if err := yaml.Unmarshal(data, &advisories); err != nil {
+		return nil, fmt.Errorf("error parsing advisories %s: %w", path, err)
+	}
+
+	var matchers []dependencyMatcher
+	for i, advisory := range advisories {
+		name, err := compileDependencyName(advisory.Name)
+		if err != nil {
+			return nil, fmt.Errorf("advisory %d: %w", i, err)
+		}
+		constraint, err := parseVersionConstraint(advisory.Affected)
+		if err != nil {
+			return nil, fmt.Errorf("advisory %s: %w", advisory.ID, err)
+		}
+		solution := ""
+		if advisory.Fixed != "" {
+			solution = fmt.Sprintf("Upgrade to %s or later", advisory.Fixed)
+		}
+		matchers = append(matchers, dependencyMatcher{
+			rule: RegexPattern{
+				ID:          advisory.ID,
+				Pattern:     advisory.Affected,
+				Description: advisory.Summary,
+				Severity:    advisorySARIFLevel(advisory.Severity),
+				MsgSolution: solution,
+			},
+			ecosystem:  advisory.Ecosystem,
+			name:       name,
+			constraint: constraint,
+		})
+	}
+	return matchers, nil
+}
+
+// advisorySARIFLevel maps advisory severities to SARIF levels, unknown severities keep the policy level
+func advisorySARIFLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "high":
+		return string(SARIFError)
+	case "moderate", "medium":
+		return string(SARIFWarning)
+	case "low":
+		return string(SARIFNote)
+	}
+	return severity
+}
+
+// unknownDependencyLicense returns the severity of packages without license data like the unknown status of _license :
+// deny reports them at the license severity, review as warnings and allow (the default) skips them
+func unknownDependencyLicense(licenses DependencyLicenses) (string, bool) {
+	switch strings.ToLower(licenses.Unknown) {
+	case "deny":
+		return licenses.Severity, true
+	case "review":
+		return string(SARIFWarning), true
+	}
+	return "", false
+}
+
+// checkDependencyLicense returns why a known license is rejected.
+// SPDX expressions pass when one OR alternative passes and all AND parts of it pass, a license WITH an
+// exception is checked as "<id> WITH <exception>" when a list names it so, otherwise as its id.
+func checkDependencyLicense(licenses DependencyLicenses, license string) string {
+	if len(licenses.Allow) == 0 && len(licenses.Deny) == 0 {
+		return ""
+	}
+	rejected := parseLicenseExpression(license).evaluate(func(id, exception string) int {
+		if term := id + " WITH " + exception; exception != "" && (containsFold(licenses.Deny, term) || containsFold(licenses.Allow, term)) {
+			id = term
+		}
+		if containsFold(licenses.Deny, id) || (len(licenses.Allow) > 0 && !containsFold(licenses.Allow, id)) {
+			return 1
+		}
+		return 0
+	})
+	if rejected == 0 {
+		return ""
+	}
+	return fmt.Sprintf("license %s is not allowed", license)
+}
+
+func GenerateDependenciesSARIFReport(policy Policy, findings []dependencyFinding) SARIFReport {
+	sarifReport := newPolicySARIFReport(policy)
+
+	sarifLevel := calculateSARIFLevel(policy, environment)
+	properties := policyResultProperties(policy)
+
+	if len(findings) == 0 {
+		sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, compliantResult(policy, "No dependency violations found"))
+	}
+
+	sources := sourceLines{}
+	for _, finding := range findings {
+		pkg := finding.pkg
+		scope := "transitive"
+		if pkg.Direct {
+			scope = "direct"
+		}
+		version := pkg.Version
+		if version == "" {
+			version = pkg.Spec
+		}
+
+		region := Region{StartLine: pkg.Line, StartColumn: 1, EndLine: pkg.Line, EndColumn: 1}
+		if lines := sources.get(pkg.File); pkg.Line >= 1 && pkg.Line <= len(lines) {
+			line := strings.TrimRight(lines[pkg.Line-1], "\r\n")
+			region.StartColumn = len(line) - len(strings.TrimLeft(line, " \t")) + 1
+			region.EndColumn = len(line) + 1
+			region.Snippet = Snippet{Text: strings.TrimSpace(line)}
+		}
+
+		result := Result{
+			RuleID: policy.ID,
+			Level:  sarifLevel,
+			Message: Message{
+				Text: fmt.Sprintf("Policy violation: %s%s %s %s@%s (%s): %s", policy.Metadata.Name, patternLabel(finding.pattern), pkg.Ecosystem, pkg.Name, version, scope, finding.reason),
+			},
+			Locations: []Location{{PhysicalLocation: PhysicalLocation{
+				ArtifactLocation: ArtifactLocation{URI: filepath.ToSlash(pkg.File)},
+				Region:           region,
+				ContextRegion:    sources.contextRegion(pkg.File, region, policy.ContextLines),
+			}}},
+			Properties: properties,
+		}
+		result.Properties.SarifInt = sarifLevelToInt(sarifLevel)
+		result.Properties.PackageURL = pkg.PURL()
+		result.Properties.DependencyScope = scope
+		result.Properties.License = pkg.License
+		applyPatternToResult(&result, finding.pattern)
+
+		sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, result)
+	}
+
+	sarifReport.Runs[0].Results = applyExceptions(virtualizeResultURIs(sarifReport.Runs[0].Results))
+	sarifReport.Runs[0].Invocations[0].Properties.ReportCompliant = ComplianceStatus(sarifReport)
+
+	if outputTypeMatrixConfig.LOG {
+		PostResultsToComplianceLog(sarifReport)
+	}
+
+	return sarifReport
+}
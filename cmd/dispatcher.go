@@ -48,6 +48,11 @@ func (d *Dispatcher) registerEvents() {
 	d.manager.On("policy.json", &PolicyEventListener{handler: d.handlePolicyJSON}, event.Normal)
 	d.manager.On("policy.ini", &PolicyEventListener{handler: d.handlePolicyINI}, event.Normal)
 	d.manager.On("policy.rego", &PolicyEventListener{handler: d.handlePolicyRego}, event.Normal)
+	d.manager.On("policy.secrets", &PolicyEventListener{handler: d.handlePolicySecrets}, event.Normal)
+	d.manager.On("policy.dependencies", &PolicyEventListener{handler: d.handlePolicyDependencies}, event.Normal)
+	d.manager.On("policy.license", &PolicyEventListener{handler: d.handlePolicyLicense}, event.Normal)
+	d.manager.On("policy.dockerfile", &PolicyEventListener{handler: d.handlePolicyDockerfile}, event.Normal)
+	d.manager.On("policy.hcl", &PolicyEventListener{handler: d.handlePolicyHCL}, event.Normal)
 }
 
 // DispatchPolicyEvent dispatches a policy event based on its type
@@ -101,3 +106,23 @@ func (d *Dispatcher) handlePolicyINI(e event.Event) error {
 func (d *Dispatcher) handlePolicyRego(e event.Event) error {
 	return processPolicyInWorker(e, "rego")
 }
+
+func (d *Dispatcher) handlePolicySecrets(e event.Event) error {
+	return processPolicyInWorker(e, "secrets")
+}
+
+func (d *Dispatcher) handlePolicyDependencies(e event.Event) error {
+	return processPolicyInWorker(e, "dependencies")
+}
+
+func (d *Dispatcher) handlePolicyLicense(e event.Event) error {
+	return processPolicyInWorker(e, "license")
+}
+
+func (d *Dispatcher) handlePolicyDockerfile(e event.Event) error {
+	return processPolicyInWorker(e, "dockerfile")
+}
+
+func (d *Dispatcher) handlePolicyHCL(e event.Event) error {
+	return processPolicyInWorker(e, "hcl")
+}
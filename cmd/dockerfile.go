@@ -0,0 +1,537 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	dockerfileName  = regexp.MustCompile(`(?i)^(?:(?:docker|container)file(?:[-._].*)?|.+\.(?:docker|container)file)$`)
+	dockerHeredoc   = regexp.MustCompile(`<<(-?)(["']?)([A-Za-z_][A-Za-z0-9_]*)(["']?)`)
+	dockerDirective = regexp.MustCompile(`^#\s*([a-zA-Z][a-zA-Z0-9]*)\s*=\s*(.+?)\s*$`)
+)
+
+// dockerFlagInstructions take --flag options before their arguments
+var dockerFlagInstructions = []string{"FROM", "RUN", "COPY", "ADD", "HEALTHCHECK"}
+
+// dockerPairInstructions declare key=value pairs
+var dockerPairInstructions = []string{"ENV", "LABEL", "ARG"}
+
+// dockerfile is the structured input of dockerfile policies, field names are the ones used in CUE and Rego
+type dockerfile struct {
+	Path         string              `json:"path"`
+	Directives   map[string]string   `json:"directives,omitempty"`
+	Args         []dockerInstruction `json:"args"`
+	Stages       []dockerStage       `json:"stages"`
+	Instructions []dockerInstruction `json:"instructions"`
+	Final        *dockerStage        `json:"final,omitempty"`
+}
+
+type dockerStage struct {
+	Index        int                 `json:"index"`
+	Name         string              `json:"name,omitempty"`
+	From         dockerImage         `json:"from"`
+	User         string              `json:"user,omitempty"`
+	Line         int                 `json:"line"`
+	EndLine      int                 `json:"end_line"`
+	Instructions []dockerInstruction `json:"instructions"`
+}
+
+// dockerImage is the base of a stage, external is false for scratch and earlier stages
+type dockerImage struct {
+	Image    string `json:"image"`
+	Name     string `json:"name"`
+	Tag      string `json:"tag,omitempty"`
+	Digest   string `json:"digest,omitempty"`
+	Platform string `json:"platform,omitempty"`
+	Stage    string `json:"stage,omitempty"`
+	Scratch  bool   `json:"scratch"`
+	External bool   `json:"external"`
+	Pinned   bool   `json:"pinned"`
+}
+
+type dockerInstruction struct {
+	Cmd      string            `json:"cmd"`
+	Flags    map[string]string `json:"flags,omitempty"`
+	Args     []string          `json:"args"`
+	Pairs    map[string]string `json:"pairs,omitempty"`
+	Value    string            `json:"value"`
+	JSON     bool              `json:"json"`
+	Heredocs []string          `json:"heredocs,omitempty"`
+	Stage    int               `json:"stage"`
+	Line     int               `json:"line"`
+	EndLine  int               `json:"end_line"`
+	Original string            `json:"original"`
+}
+
+// dockerfileViolation is a failed check located on the lines of an instruction
+type dockerfileViolation struct {
+	file        string
+	line        int
+	endLine     int
+	message     string
+	instruction string
+	stage       string
+}
+
+// ProcessDockerfileType handles policies of type "dockerfile"
+func ProcessDockerfileType(policy Policy, targetDir string, filePaths []string) error {
+	if policy.Type != "dockerfile" {
+		return nil
+	}
+
+	violations, err := executeDockerfile(policy, filePaths)
+	if err != nil {
+		log.Error().Err(err).Str("policy", policy.ID).Msg("Error evaluating dockerfile policy")
+		return fmt.Errorf("error evaluating dockerfile policy %s: %w", policy.ID, err)
+	}
+	sarifReport := GenerateDockerfileSARIFReport(policy, violations)
+
+	reportID := policy.ID
+	if policy.RunID != "" {
+		reportID = policy.RunID
+	}
+	if err := writeSARIFReport(reportID, sarifReport); err != nil {
+		log.Error().Err(err).Msg("error writing SARIF report")
+		return fmt.Errorf("error writing SARIF report: %w", err)
+	}
+
+	log.Debug().Msgf("Policy %s processed. %d dockerfile violations found", policy.ID, len(violations))
+	return nil
+}
+
+func executeDockerfile(policy Policy, filePaths []string) ([]dockerfileViolation, error) {
+	evaluate, err := compileStructuredEvaluator(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// without filepattern or include the policy picks the Dockerfiles of the target
+	byName := policy.FilePattern == "" && len(policy.Include) == 0
+
+	var violations []dockerfileViolation
+	for _, file := range filePaths {
+		if byName && !dockerfileName.MatchString(filepath.Base(file)) {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Debug().Err(err).Str("file", file).Msg("dockerfile skipped unreadable file")
+			continue
+		}
+
+		parsed, problems := parseDockerfile(file, string(content))
+		for _, problem := range problems {
+			problem.file = file
+			violations = append(violations, problem)
+		}
+
+		input, err := dockerfileInput(parsed)
+		if err != nil {
+			return nil, err
+		}
+		found, err := evaluate(input)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, found := range found {
+			violation := dockerfileViolation{file: file, line: found.line, endLine: found.endLine, message: found.message}
+			if found.path != nil {
+				violation.line, violation.endLine = dockerfilePathLines(input, found.path)
+			}
+			if violation.line < 1 {
+				violation.line = 1
+			}
+			var endLine int
+			violation.instruction, violation.stage, endLine = parsed.locate(violation.line)
+			if violation.endLine < endLine {
+				violation.endLine = endLine
+			}
+			if violation.endLine < violation.line {
+				violation.endLine = violation.line
+			}
+			violations = append(violations, violation)
+		}
+	}
+	return violations, nil
+}
+
+// dockerfileInput converts a parsed Dockerfile to the generic value given to CUE and Rego
+func dockerfileInput(parsed dockerfile) (map[string]interface{}, error) {
+	return structuredInput(parsed)
+}
+
+// dockerfilePathLines follows a CUE error path through the input, the deepest object with a line locates the error :
+// stages are located on their FROM instruction, or their last USER instruction for errors on user
+func dockerfilePathLines(input map[string]interface{}, path []string) (int, int) {
+	line, endLine := 1, 1
+	var current interface{} = input
+	for _, selector := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[selector]
+			if _, isStage := node["from"]; isStage && selector == "user" {
+				line, endLine = dockerStageLines(node, "USER", line, endLine)
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(selector)
+			if err != nil || index < 0 || index >= len(node) {
+				return line, endLine
+			}
+			current = node[index]
+		default:
+			return line, endLine
+		}
+
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, isStage := object["from"]; isStage {
+			line, endLine = dockerStageLines(object, "FROM", line, endLine)
+		} else if l := regoInt(object["line"], 0); l > 0 {
+			line = l
+			endLine = regoInt(object["end_line"], l)
+		}
+	}
+	return line, endLine
+}
+
+// dockerStageLines returns the lines of the last cmd instruction of a stage
+func dockerStageLines(stage map[string]interface{}, cmd string, line, endLine int) (int, int) {
+	instructions, _ := stage["instructions"].([]interface{})
+	for _, item := range instructions {
+		if instruction, ok := item.(map[string]interface{}); ok && instruction["cmd"] == cmd {
+			line = regoInt(instruction["line"], line)
+			endLine = regoInt(instruction["end_line"], line)
+		}
+	}
+	return line, endLine
+}
+
+// parseDockerfile splits a Dockerfile into instructions and stages, problems are reported as violations
+func parseDockerfile(file, content string) (dockerfile, []dockerfileViolation) {
+	parsed := dockerfile{Path: filepath.ToSlash(file), Args: []dockerInstruction{}, Stages: []dockerStage{}, Instructions: []dockerInstruction{}}
+	var problems []dockerfileViolation
+
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	escape := byte('\\')
+
+	// parser directives are only read before any other line
+	i := 0
+	for ; i < len(lines); i++ {
+		match := dockerDirective.FindStringSubmatch(strings.TrimSpace(lines[i]))
+		if match == nil {
+			break
+		}
+		if parsed.Directives == nil {
+			parsed.Directives = make(map[string]string)
+		}
+		key := strings.ToLower(match[1])
+		parsed.Directives[key] = match[2]
+		if key == "escape" && (match[2] == "`" || match[2] == `\`) {
+			escape = match[2][0]
+		}
+	}
+
+	stages := make(map[string]int)
+	globalArgs := make(map[string]string)
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		start := i
+		original := []string{lines[i]}
+		text := strings.TrimRight(lines[i], " \t")
+		for strings.HasSuffix(text, string(escape)) && i+1 < len(lines) {
+			text = text[:len(text)-1]
+			i++
+			original = append(original, lines[i])
+			next := strings.TrimSpace(lines[i])
+			// comments and empty lines inside a continuation are dropped
+			if next == "" || strings.HasPrefix(next, "#") {
+				text += " " + string(escape)
+				continue
+			}
+			text += " " + strings.TrimRight(lines[i], " \t")
+		}
+		text = strings.TrimSuffix(strings.TrimRight(text, " \t"), string(escape))
+
+		instruction := parseDockerInstruction(text)
+		instruction.Line = start + 1
+
+		if containsString([]string{"RUN", "COPY", "ADD"}, instruction.Cmd) {
+			for _, match := range dockerHeredoc.FindAllStringSubmatch(instruction.Value, -1) {
+				delimiter, stripTabs := match[3], match[1] == "-"
+				var body []string
+				terminated := false
+				for i+1 < len(lines) {
+					i++
+					original = append(original, lines[i])
+					candidate := lines[i]
+					if stripTabs {
+						candidate = strings.TrimLeft(candidate, "\t")
+					}
+					if candidate == delimiter {
+						terminated = true
+						break
+					}
+					body = append(body, candidate)
+				}
+				if !terminated {
+					problems = append(problems, dockerfileViolation{line: instruction.Line, endLine: i + 1, message: fmt.Sprintf("invalid Dockerfile: unterminated heredoc %s", delimiter)})
+				}
+				instruction.Heredocs = append(instruction.Heredocs, strings.Join(body, "\n"))
+			}
+		}
+		instruction.EndLine = i + 1
+		instruction.Original = strings.Join(original, "\n")
+
+		if instruction.Cmd == "FROM" {
+			stage := dockerStage{Index: len(parsed.Stages), Line: instruction.Line, Instructions: []dockerInstruction{}}
+			stage.From, stage.Name = parseDockerFrom(instruction, stages, globalArgs)
+			if stage.Name != "" {
+				stages[stage.Name] = stage.Index
+			}
+			if stage.From.Stage != "" {
+				// a stage built on an earlier one starts with its user
+				stage.User = parsed.Stages[stages[stage.From.Stage]].User
+			}
+			parsed.Stages = append(parsed.Stages, stage)
+		}
+
+		if len(parsed.Stages) == 0 {
+			instruction.Stage = -1
+			if instruction.Cmd != "ARG" {
+				problems = append(problems, dockerfileViolation{line: instruction.Line, endLine: instruction.EndLine, message: fmt.Sprintf("invalid Dockerfile: %s before the first FROM", instruction.Cmd), instruction: instruction.Cmd})
+			}
+			for key, value := range instruction.Pairs {
+				globalArgs[key] = value
+			}
+			parsed.Args = append(parsed.Args, instruction)
+			parsed.Instructions = append(parsed.Instructions, instruction)
+			continue
+		}
+
+		stage := &parsed.Stages[len(parsed.Stages)-1]
+		instruction.Stage = stage.Index
+		if instruction.Cmd == "USER" && len(instruction.Args) > 0 {
+			stage.User = instruction.Args[0]
+		}
+		stage.EndLine = instruction.EndLine
+		stage.Instructions = append(stage.Instructions, instruction)
+		parsed.Instructions = append(parsed.Instructions, instruction)
+	}
+
+	if len(parsed.Stages) == 0 {
+		problems = append(problems, dockerfileViolation{line: 1, endLine: 1, message: "invalid Dockerfile: no FROM instruction"})
+	} else {
+		final := parsed.Stages[len(parsed.Stages)-1]
+		parsed.Final = &final
+	}
+	return parsed, problems
+}
+
+// parseDockerInstruction reads the keyword, the --flags and the exec (JSON) or shell form arguments
+func parseDockerInstruction(text string) dockerInstruction {
+	fields := strings.SplitN(strings.TrimSpace(text), " ", 2)
+	instruction := dockerInstruction{Cmd: strings.ToUpper(strings.TrimSpace(fields[0])), Args: []string{}}
+	rest := ""
+	if len(fields) > 1 {
+		rest = strings.TrimSpace(fields[1])
+	}
+
+	if containsString(dockerFlagInstructions, instruction.Cmd) {
+		for strings.HasPrefix(rest, "--") {
+			flag := rest
+			if end := strings.IndexAny(rest, " \t"); end >= 0 {
+				flag, rest = rest[:end], strings.TrimSpace(rest[end:])
+			} else {
+				rest = ""
+			}
+			if instruction.Flags == nil {
+				instruction.Flags = make(map[string]string)
+			}
+			name, value, _ := strings.Cut(strings.TrimPrefix(flag, "--"), "=")
+			instruction.Flags[strings.ToLower(name)] = value
+		}
+	}
+	instruction.Value = rest
+
+	if strings.HasPrefix(rest, "[") {
+		var args []string
+		if err := json.Unmarshal([]byte(rest), &args); err == nil {
+			instruction.Args = args
+			instruction.JSON = true
+			return instruction
+		}
+	}
+	instruction.Args = splitShellWords(rest)
+
+	if containsString(dockerPairInstructions, instruction.Cmd) {
+		instruction.Pairs = make(map[string]string)
+		if len(instruction.Args) > 1 && instruction.Cmd == "ENV" && !strings.Contains(instruction.Args[0], "=") {
+			// legacy ENV key value form
+			key, value, _ := strings.Cut(rest, " ")
+			instruction.Pairs[key] = strings.TrimSpace(value)
+			return instruction
+		}
+		for _, arg := range instruction.Args {
+			key, value, _ := strings.Cut(arg, "=")
+			instruction.Pairs[key] = value
+		}
+	}
+	return instruction
+}
+
+// parseDockerFrom reads FROM [--platform=...] image[:tag][@digest] [AS name], the ARGs declared before
+// the first FROM are expanded in the image
+func parseDockerFrom(instruction dockerInstruction, stages map[string]int, globalArgs map[string]string) (dockerImage, string) {
+	image := dockerImage{Platform: instruction.Flags["platform"]}
+	var name string
+	if len(instruction.Args) > 0 {
+		image.Image = os.Expand(instruction.Args[0], func(variable string) string {
+			key, fallback, hasFallback := strings.Cut(variable, ":-")
+			if value, ok := globalArgs[key]; ok && value != "" {
+				return value
+			}
+			if hasFallback {
+				return fallback
+			}
+			return "${" + variable + "}"
+		})
+	}
+	if len(instruction.Args) >= 3 && strings.EqualFold(instruction.Args[1], "as") {
+		name = strings.ToLower(instruction.Args[2])
+	}
+
+	ref := image.Image
+	if at := strings.Index(ref, "@"); at >= 0 {
+		image.Digest = ref[at+1:]
+		ref = ref[:at]
+	}
+	if colon := strings.LastIndex(ref, ":"); colon > strings.LastIndex(ref, "/") {
+		image.Tag = ref[colon+1:]
+		ref = ref[:colon]
+	}
+	image.Name = ref
+
+	switch _, ok := stages[strings.ToLower(image.Image)]; {
+	case ok:
+		image.Stage = strings.ToLower(image.Image)
+	case strings.EqualFold(image.Image, "scratch"):
+		image.Scratch = true
+	default:
+		image.External = true
+	}
+	image.Pinned = image.Digest != ""
+	return image, name
+}
+
+// splitShellWords splits on blanks outside of quotes and removes the quotes
+func splitShellWords(text string) []string {
+	words := []string{}
+	var word strings.Builder
+	var quote byte
+	inWord := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteByte(c)
+		case c == '"' || c == '\'':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// locate returns the instruction found on a line, its stage and its last line
+func (d dockerfile) locate(line int) (string, string, int) {
+	for _, instruction := range d.Instructions {
+		if line < instruction.Line || line > instruction.EndLine {
+			continue
+		}
+		if instruction.Stage < 0 {
+			return instruction.Cmd, "", instruction.EndLine
+		}
+		stage := d.Stages[instruction.Stage]
+		if stage.Name != "" {
+			return instruction.Cmd, stage.Name, instruction.EndLine
+		}
+		return instruction.Cmd, strconv.Itoa(stage.Index), instruction.EndLine
+	}
+	return "", "", 0
+}
+
+func GenerateDockerfileSARIFReport(policy Policy, violations []dockerfileViolation) SARIFReport {
+	sarifReport := newPolicySARIFReport(policy)
+
+	properties := policyResultProperties(policy)
+
+	if len(violations) == 0 {
+		sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, compliantResult(policy, "No Dockerfile violations found"))
+	}
+
+	level := calculateSARIFLevel(policy, environment)
+	sources := sourceLines{}
+	for _, violation := range violations {
+		region := Region{StartLine: violation.line, StartColumn: 1, EndLine: violation.endLine, EndColumn: 1}
+		if lines := sources.get(violation.file); violation.line <= len(lines) {
+			end := violation.endLine
+			if end > len(lines) {
+				end = len(lines)
+			}
+			snippet := strings.TrimRight(strings.Join(lines[violation.line-1:end], ""), "\r\n")
+			region.EndColumn = len(strings.TrimRight(lines[end-1], "\r\n")) + 1
+			region.Snippet = Snippet{Text: strings.TrimSpace(snippet)}
+		}
+
+		result := Result{
+			RuleID:  policy.ID,
+			Level:   level,
+			Message: Message{Text: fmt.Sprintf("Policy violation: %s %s", policy.Metadata.Name, violation.message)},
+			Locations: []Location{{PhysicalLocation: PhysicalLocation{
+				ArtifactLocation: ArtifactLocation{URI: filepath.ToSlash(violation.file)},
+				Region:           region,
+				ContextRegion:    sources.contextRegion(violation.file, region, policy.ContextLines),
+			}}},
+			Properties: properties,
+		}
+		result.Properties.SarifInt = sarifLevelToInt(level)
+		result.Properties.Instruction = violation.instruction
+		result.Properties.Stage = violation.stage
+
+		sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, result)
+	}
+
+	sarifReport.Runs[0].Results = applyExceptions(virtualizeResultURIs(sarifReport.Runs[0].Results))
+	sarifReport.Runs[0].Invocations[0].Properties.ReportCompliant = ComplianceStatus(sarifReport)
+
+	if outputTypeMatrixConfig.LOG {
+		PostResultsToComplianceLog(sarifReport)
+	}
+
+	return sarifReport
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Exception waives the findings of one policy on the matching paths until it expires
+type Exception struct {
+	ID            string `yaml:"id,omitempty"`
+	PolicyID      string `yaml:"policy_id,omitempty"`
+	Path          string `yaml:"path"`
+	Line          int    `yaml:"line,omitempty"`
+	SnippetHash   string `yaml:"snippet_sha256,omitempty"`
+	Owner         string `yaml:"owner"`
+	Justification string `yaml:"justification"`
+	Expires       string `yaml:"expires,omitempty"`
+
+	expiresAt time.Time
+	kind      string
+}
+
+// ExceptionsFile is the standalone exceptions file format
+type ExceptionsFile struct {
+	Exceptions []Exception `yaml:"Exceptions"`
+}
+
+const (
+	exceptionDateFormat = "2006-01-02"
+
+	ExceptionStatusSuppressed = "suppressed"
+	ExceptionStatusExpired    = "expired"
+)
+
+var (
+	exceptionList  []Exception
+	exceptionMutex sync.RWMutex
+	exceptionsFile string
+)
+
+// LoadExceptions collects the inline policy exceptions and the optional exceptions file into the active exception list
+func LoadExceptions(policyFile *PolicyFile, filename string) error {
+	var exceptions []Exception
+
+	for _, policy := range policyFile.Policies {
+		for _, exception := range policy.Exceptions {
+			exception.PolicyID = policy.ID
+			exception.kind = "inSource"
+			if err := prepareException(&exception); err != nil {
+				return fmt.Errorf("invalid exception on policy %s: %w", policy.ID, err)
+			}
+			exceptions = append(exceptions, exception)
+		}
+	}
+
+	if filename != "" {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("error reading exceptions file %s: %w", filename, err)
+		}
+
+		var exFile ExceptionsFile
+		if err := yaml.Unmarshal(data, &exFile); err != nil {
+			return fmt.Errorf("error parsing exceptions file %s: %w", filename, err)
+		}
+
+		for i, exception := range exFile.Exceptions {
+			if exception.PolicyID == "" {
+				return fmt.Errorf("exception #%d in %s has no policy_id", i+1, filename)
+			}
+			exception.PolicyID = NormalizePolicyName(exception.PolicyID)
+			exception.kind = "external"
+			if err := prepareException(&exception); err != nil {
+				return fmt.Errorf("invalid exception #%d in %s: %w", i+1, filename, err)
+			}
+			exceptions = append(exceptions, exception)
+		}
+	}
+
+	exceptionMutex.Lock()
+	exceptionList = exceptions
+	exceptionMutex.Unlock()
+
+	log.Debug().Int("exceptions", len(exceptions)).Msg("Exceptions loaded")
+
+	return nil
+}
+
+func prepareException(exception *Exception) error {
+	if exception.Path == "" {
+		return fmt.Errorf("path glob is required")
+	}
+	if _, err := compileGlob(exception.Path); err != nil {
+		return err
+	}
+	if strings.TrimSpace(exception.Justification) == "" {
+		return fmt.Errorf("justification is required")
+	}
+	if exception.Owner == "" {
+		log.Warn().Str("policy", exception.PolicyID).Str("path", exception.Path).Msg("Exception without an owner")
+	}
+	if exception.Expires != "" {
+		expires, err := time.Parse(exceptionDateFormat, exception.Expires)
+		if err != nil {
+			return fmt.Errorf("invalid expires date %q (expected YYYY-MM-DD): %w", exception.Expires, err)
+		}
+		// an exception is valid until the end of its expiry day
+		exception.expiresAt = expires.Add(24 * time.Hour)
+	}
+	exception.SnippetHash = strings.ToLower(exception.SnippetHash)
+	if exception.ID == "" {
+		exception.ID = fmt.Sprintf("%s:%s", exception.PolicyID, exception.Path)
+		if exception.Line > 0 {
+			exception.ID = fmt.Sprintf("%s:%d", exception.ID, exception.Line)
+		}
+	}
+	return nil
+}
+
+// matches checks if the exception applies to a given result
+func (e Exception) matches(result Result) bool {
+	if e.PolicyID != result.RuleID {
+		return false
+	}
+	if len(result.Locations) == 0 {
+		return false
+	}
+	for _, location := range result.Locations {
+		uri := location.PhysicalLocation.ArtifactLocation.URI
+		if !matchExceptionPath(e.Path, uri) {
+			continue
+		}
+		if e.Line > 0 && location.PhysicalLocation.Region.StartLine != e.Line {
+			continue
+		}
+		if e.SnippetHash != "" && hashSnippet(location.PhysicalLocation.Region.Snippet.Text) != e.SnippetHash {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (e Exception) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// matchExceptionPath matches the exception glob against the result URI as reported, relative to the target
+// and, for archive targets, relative to the archive
+func matchExceptionPath(pattern, uri string) bool {
+	if uri == "" || uri == "N/A" {
+		return false
+	}
+	if matchGlob(pattern, uri) {
+		return true
+	}
+	if _, inner, ok := strings.Cut(uri, archivePathSeparator); ok && matchGlob(pattern, inner) {
+		return true
+	}
+	if targetDir != "" {
+		if rel, err := filepath.Rel(targetDir, uri); err == nil && !strings.HasPrefix(rel, "..") {
+			return matchGlob(pattern, rel)
+		}
+	}
+	return false
+}
+
+// hashSnippet returns the SHA256 used to pin an exception to the matched content
+func hashSnippet(text string) string {
+	hash := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(hash[:])
+}
+
+// applyExceptions marks results covered by an active exception with a SARIF suppression,
+// results covered only by expired exceptions stay active and are flagged as such
+func applyExceptions(results []Result) []Result {
+	exceptionMutex.RLock()
+	defer exceptionMutex.RUnlock()
+
+	if len(exceptionList) == 0 {
+		return results
+	}
+
+	now := time.Now()
+
+	for i := range results {
+		if results[i].Level != SARIFError && results[i].Level != SARIFWarning {
+			continue
+		}
+		for _, exception := range exceptionList {
+			if !exception.matches(results[i]) {
+				continue
+			}
+			if exception.expired(now) {
+				results[i].Properties.ExceptionStatus = ExceptionStatusExpired
+				results[i].Properties.ExceptionID = exception.ID
+				log.Warn().Str("policy", exception.PolicyID).Str("exception", exception.ID).Str("expired", exception.Expires).Msg("Exception expired, finding re-activated")
+				continue
+			}
+			results[i].Suppressions = append(results[i].Suppressions, Suppression{
+				Kind:          exception.kind,
+				Status:        "accepted",
+				Justification: exception.Justification,
+				Properties: SuppressionProperties{
+					ExceptionID: exception.ID,
+					Owner:       exception.Owner,
+					Expires:     exception.Expires,
+				},
+			})
+			results[i].Properties.ExceptionStatus = ExceptionStatusSuppressed
+			results[i].Properties.ExceptionID = exception.ID
+			break
+		}
+	}
+
+	return results
+}
+
+// isSuppressed reports whether a result has an accepted suppression and must not affect compliance
+func isSuppressed(result Result) bool {
+	for _, suppression := range result.Suppressions {
+		if suppression.Status == "" || suppression.Status == "accepted" {
+			return true
+		}
+	}
+	return false
+}
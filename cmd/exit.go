@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Exit codes returned by audit based on the worst SARIF level of the merged report
+const (
+	ExitCodeClean    = 0
+	ExitCodeWarning  = 1
+	ExitCodeCritical = 2
+)
+
+// failOnNever disables any non-zero exit code driven by compliance status
+const failOnNever = "never"
+
+// WorstSARIFLevel returns the most severe level found across all results of a report
+func WorstSARIFLevel(sarifReport SARIFReport) SARIFLevel {
+	worst := SARIFNone
+	for _, run := range sarifReport.Runs {
+		for _, result := range run.Results {
+			if isSuppressed(result) || isBaselineFinding(result) {
+				continue
+			}
+			level := sarifLevelToInt(result.Level)
+			if level == 99 {
+				continue // unknown level
+			}
+			if level > sarifLevelToInt(worst) {
+				worst = result.Level
+			}
+		}
+	}
+	return worst
+}
+
+// parseFailOn validates the fail-on threshold and returns its integer SARIF level
+func parseFailOn(failOn string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(failOn)) {
+	case "error":
+		return sarifLevelToInt(SARIFError), nil
+	case "warning", "":
+		return sarifLevelToInt(SARIFWarning), nil
+	case failOnNever:
+		return 99, nil
+	default:
+		return 0, fmt.Errorf("invalid fail-on threshold %q (expected error, warning or never)", failOn)
+	}
+}
+
+// exitCodeForLevel maps the worst SARIF level of a report to an exit code, honouring the fail-on threshold
+func exitCodeForLevel(worst SARIFLevel, threshold int) int {
+	if sarifLevelToInt(worst) < threshold {
+		return ExitCodeClean
+	}
+	switch worst {
+	case SARIFError:
+		return ExitCodeCritical
+	case SARIFWarning:
+		return ExitCodeWarning
+	default:
+		return ExitCodeClean
+	}
+}
+
+// exitMessageForLevel returns the configured exit message matching the worst SARIF level
+func exitMessageForLevel(config Config, worst SARIFLevel) string {
+	switch worst {
+	case SARIFError:
+		return config.Metadata.MsgExitCritical
+	case SARIFWarning:
+		return config.Metadata.MsgExitWarning
+	default:
+		return config.Metadata.MsgExitClean
+	}
+}
@@ -0,0 +1,518 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// fileFormat parses target files into the JSON tree checked by a _schema structure or passed as the
+// _rego input. Formats without parse are converted by convertToJSON, patch formats can be written back
+// by _schema.patch
+type fileFormat struct {
+	description string
+	patch       bool
+	parse       func(content []byte) (interface{}, error)
+}
+
+// fileFormats are the parsers selected by the format field of json, yml, toml, ini and rego policies
+var fileFormats = map[string]fileFormat{
+	"json":       {description: "JSON", patch: true},
+	"yaml":       {description: "YAML", patch: true},
+	"toml":       {description: "TOML", patch: true},
+	"ini":        {description: "INI sections and keys", patch: true},
+	"xml":        {description: "XML elements, @attributes and #text", parse: parseXMLFormat},
+	"properties": {description: "Java .properties keys", parse: parsePropertiesFormat},
+	"env":        {description: "dotenv variables", parse: parseEnvFormat},
+	"nginx":      {description: "nginx directives and blocks", parse: parseNginxFormat},
+	"apache":     {description: "Apache httpd directives and sections", parse: parseApacheFormat},
+}
+
+// formatPolicyTypes are the policy types reading their files with the format field
+var formatPolicyTypes = []string{"json", "yml", "toml", "ini", "rego"}
+
+func sortedFileFormats() []string {
+	names := make([]string, 0, len(fileFormats))
+	for name := range fileFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// policyFormat returns the format of the files of a policy, native when the policy has no format
+func policyFormat(policy Policy, native string) string {
+	if policy.Format != "" {
+		return strings.ToLower(policy.Format)
+	}
+	return native
+}
+
+// parseFileFormat parses content with a registered format into plain JSON values
+func parseFileFormat(format string, content []byte) (interface{}, error) {
+	if _, ok := fileFormats[format]; !ok {
+		return nil, fmt.Errorf("unknown format %q (expected one of %s)", format, strings.Join(sortedFileFormats(), ","))
+	}
+	data, err := convertToJSON(content, format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", format, err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// xmlElement collects the attributes, children and text of an element while it is decoded
+type xmlElement struct {
+	name     string
+	fields   map[string]interface{}
+	children map[string][]interface{}
+	order    []string
+	text     strings.Builder
+}
+
+// parseXMLFormat converts the root element to {name: element}. Attributes are @name fields, child
+// elements are lists even when they appear once, text next to attributes or children is #text and
+// elements with only text are strings
+func parseXMLFormat(content []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	// encodings other than UTF-8 (ISO-8859-1 poms) are read as is, their ASCII content is unchanged
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	var stack []*xmlElement
+	var root map[string]interface{}
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			element := &xmlElement{name: t.Name.Local, fields: make(map[string]interface{}), children: make(map[string][]interface{})}
+			for _, attr := range t.Attr {
+				name := attr.Name.Local
+				if attr.Name.Space == "xmlns" {
+					name = "xmlns:" + name
+				}
+				element.fields["@"+name] = attr.Value
+			}
+			stack = append(stack, element)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		case xml.EndElement:
+			element := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			value := element.value()
+			if len(stack) == 0 {
+				root = map[string]interface{}{element.name: value}
+				continue
+			}
+			parent := stack[len(stack)-1]
+			if _, ok := parent.children[element.name]; !ok {
+				parent.order = append(parent.order, element.name)
+			}
+			parent.children[element.name] = append(parent.children[element.name], value)
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+	return root, nil
+}
+
+func (e *xmlElement) value() interface{} {
+	text := strings.TrimSpace(e.text.String())
+	if len(e.fields) == 0 && len(e.order) == 0 {
+		return text
+	}
+	for _, name := range e.order {
+		e.fields[name] = e.children[name]
+	}
+	if text != "" {
+		e.fields["#text"] = text
+	}
+	return e.fields
+}
+
+// parsePropertiesFormat reads Java .properties files : key=value, key:value or key value lines,
+// # and ! comments, backslash continuations and escapes. Values are strings, later keys win
+func parsePropertiesFormat(content []byte) (interface{}, error) {
+	properties := make(map[string]interface{})
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// an odd number of trailing backslashes continues the line
+		for endsWithContinuation(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		if endsWithContinuation(line) {
+			line = line[:len(line)-1]
+		}
+
+		keyEnd := len(line)
+		for j := 0; j < len(line); j++ {
+			if line[j] == '\\' {
+				j++
+				continue
+			}
+			if strings.IndexByte("=: \t\f", line[j]) >= 0 {
+				keyEnd = j
+				break
+			}
+		}
+		value := strings.TrimLeft(line[keyEnd:], " \t\f")
+		if value != "" && (value[0] == '=' || value[0] == ':') {
+			value = strings.TrimLeft(value[1:], " \t\f")
+		}
+		properties[unescapeProperty(line[:keyEnd])] = unescapeProperty(value)
+	}
+	return properties, nil
+}
+
+func endsWithContinuation(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count%2 == 1
+}
+
+func unescapeProperty(text string) string {
+	if !strings.Contains(text, `\`) {
+		return text
+	}
+	var out strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			out.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 't':
+			out.WriteByte('\t')
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 'f':
+			out.WriteByte('\f')
+		case 'u':
+			if i+4 < len(text) {
+				if code, err := strconv.ParseUint(text[i+1:i+5], 16, 32); err == nil {
+					out.WriteRune(rune(code))
+					i += 4
+					continue
+				}
+			}
+			out.WriteByte('u')
+		default:
+			out.WriteByte(text[i])
+		}
+	}
+	return out.String()
+}
+
+// parseEnvFormat reads dotenv files : KEY=value lines with an optional export, # comments, single
+// quoted literal values and double quoted values with escapes, both can span lines. Variables are
+// not expanded
+func parseEnvFormat(content []byte) (interface{}, error) {
+	variables := make(map[string]interface{})
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.ContainsAny(key, " \t\"'") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", i+1)
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote := value[0]
+			start := i
+			text := value[1:]
+			for {
+				if end := closingQuote(text, quote); end >= 0 {
+					value = text[:end]
+					break
+				}
+				if i+1 == len(lines) {
+					return nil, fmt.Errorf("line %d: unterminated %c quoted value", start+1, quote)
+				}
+				i++
+				text += "\n" + lines[i]
+			}
+			if quote == '"' {
+				value = unescapeEnv(value)
+			}
+			variables[key] = value
+			continue
+		}
+		if comment := strings.Index(value, " #"); comment >= 0 {
+			value = value[:comment]
+		}
+		variables[key] = strings.TrimRight(value, " \t")
+	}
+	return variables, nil
+}
+
+func closingQuote(text string, quote byte) int {
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && quote == '"':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeEnv(text string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+	return replacer.Replace(text)
+}
+
+// configDirective is a directive of an nginx or Apache configuration, block holds the directives of
+// its block or section
+type configDirective struct {
+	Args  []string    `json:"args"`
+	Line  int         `json:"line"`
+	Block configBlock `json:"block,omitempty"`
+}
+
+// configBlock groups directives by name, repeated directives keep their order
+type configBlock map[string][]configDirective
+
+func (b configBlock) add(name string, directive configDirective) {
+	b[name] = append(b[name], directive)
+}
+
+// configToken is a word of an nginx configuration, quoted words are never ; { or }
+type configToken struct {
+	text   string
+	line   int
+	quoted bool
+}
+
+// parseNginxFormat reads nginx configurations into {name: [{args, line, block}]} trees, include
+// directives are not followed
+func parseNginxFormat(content []byte) (interface{}, error) {
+	if !utf8.Valid(content) {
+		return nil, fmt.Errorf("not UTF-8 text")
+	}
+	tokens, err := nginxTokens(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	type openBlock struct {
+		block configBlock
+		name  string
+		line  int
+	}
+	root := configBlock{}
+	stack := []openBlock{{block: root}}
+	var words []configToken
+	for _, token := range tokens {
+		current := stack[len(stack)-1]
+		switch {
+		case !token.quoted && token.text == ";":
+			if len(words) == 0 {
+				return nil, fmt.Errorf("line %d: unexpected \";\"", token.line)
+			}
+			current.block.add(words[0].text, configDirective{Args: tokenTexts(words[1:]), Line: words[0].line})
+			words = nil
+		case !token.quoted && token.text == "{":
+			if len(words) == 0 {
+				return nil, fmt.Errorf("line %d: unexpected \"{\"", token.line)
+			}
+			stack = append(stack, openBlock{block: configBlock{}, name: words[0].text, line: words[0].line})
+			current.block.add(words[0].text, configDirective{Args: tokenTexts(words[1:]), Line: words[0].line})
+			words = nil
+		case !token.quoted && token.text == "}":
+			if len(words) > 0 {
+				return nil, fmt.Errorf("line %d: directive %q is not terminated by \";\"", words[0].line, words[0].text)
+			}
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("line %d: unexpected \"}\"", token.line)
+			}
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1].block[current.name]
+			parent[len(parent)-1].Block = current.block
+		default:
+			words = append(words, token)
+		}
+	}
+	if len(words) > 0 {
+		return nil, fmt.Errorf("line %d: directive %q is not terminated by \";\"", words[0].line, words[0].text)
+	}
+	if len(stack) > 1 {
+		unclosed := stack[len(stack)-1]
+		return nil, fmt.Errorf("line %d: block %q is not closed", unclosed.line, unclosed.name)
+	}
+	return root, nil
+}
+
+func nginxTokens(content string) ([]configToken, error) {
+	var tokens []configToken
+	line := 1
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\n':
+			line++
+		case c == ' ' || c == '\t' || c == '\r':
+		case c == '#':
+			for i+1 < len(content) && content[i+1] != '\n' {
+				i++
+			}
+		case c == ';' || c == '{' || c == '}':
+			tokens = append(tokens, configToken{text: string(c), line: line})
+		case c == '"' || c == '\'':
+			start := line
+			var word strings.Builder
+			for i++; ; i++ {
+				if i == len(content) {
+					return nil, fmt.Errorf("line %d: unterminated %c quoted string", start, c)
+				}
+				// \" and \\ are escapes, other backslashes belong to regexes
+				if content[i] == '\\' && i+1 < len(content) && (content[i+1] == c || content[i+1] == '\\') {
+					i++
+				} else if content[i] == c {
+					break
+				}
+				if content[i] == '\n' {
+					line++
+				}
+				word.WriteByte(content[i])
+			}
+			tokens = append(tokens, configToken{text: word.String(), line: start, quoted: true})
+		default:
+			start := i
+			// ${var} and regex quantifiers {1,3} stay inside the word
+			depth := 0
+			for ; i < len(content); i++ {
+				d := content[i]
+				if d == '{' && i > start && (content[i-1] == '$' || depth > 0 || isNginxQuantifier(content[i:])) {
+					depth++
+					continue
+				}
+				if d == '}' && depth > 0 {
+					depth--
+					continue
+				}
+				if strings.IndexByte(" \t\r\n;{}", d) >= 0 {
+					break
+				}
+			}
+			tokens = append(tokens, configToken{text: content[start:i], line: line})
+			i--
+		}
+	}
+	return tokens, nil
+}
+
+// isNginxQuantifier reports regex quantifiers ({3}, {1,3}) inside unquoted location patterns
+func isNginxQuantifier(text string) bool {
+	end := strings.IndexByte(text, '}')
+	if end < 2 {
+		return false
+	}
+	for _, c := range text[1:end] {
+		if (c < '0' || c > '9') && c != ',' {
+			return false
+		}
+	}
+	return true
+}
+
+func tokenTexts(tokens []configToken) []string {
+	texts := make([]string, len(tokens))
+	for i, token := range tokens {
+		texts[i] = token.text
+	}
+	return texts
+}
+
+// parseApacheFormat reads Apache httpd configurations (<Section args> ... </Section>, one directive
+// per line, backslash continuations) into the same tree as nginx, Include is not followed
+func parseApacheFormat(content []byte) (interface{}, error) {
+	if !utf8.Valid(content) {
+		return nil, fmt.Errorf("not UTF-8 text")
+	}
+
+	type openSection struct {
+		block configBlock
+		name  string
+		line  int
+	}
+	root := configBlock{}
+	stack := []openSection{{block: root}}
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		line := strings.TrimSpace(lines[i])
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, `\`) + " " + strings.TrimSpace(lines[i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		current := stack[len(stack)-1]
+
+		switch {
+		case strings.HasPrefix(line, "</"):
+			name := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "</"), ">"))
+			if len(stack) == 1 || !strings.EqualFold(name, current.name) {
+				return nil, fmt.Errorf("line %d: unexpected </%s>", start, name)
+			}
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1].block[current.name]
+			parent[len(parent)-1].Block = current.block
+		case strings.HasPrefix(line, "<"):
+			if !strings.HasSuffix(line, ">") {
+				return nil, fmt.Errorf("line %d: section is not terminated by \">\"", start)
+			}
+			words := splitShellWords(strings.TrimSuffix(line[1:], ">"))
+			if len(words) == 0 {
+				return nil, fmt.Errorf("line %d: empty section", start)
+			}
+			current.block.add(words[0], configDirective{Args: words[1:], Line: start})
+			stack = append(stack, openSection{block: configBlock{}, name: words[0], line: start})
+		default:
+			words := splitShellWords(line)
+			current.block.add(words[0], configDirective{Args: words[1:], Line: start})
+		}
+	}
+	if len(stack) > 1 {
+		unclosed := stack[len(stack)-1]
+		return nil, fmt.Errorf("line %d: section <%s> is not closed", unclosed.line, unclosed.name)
+	}
+	return root, nil
+}
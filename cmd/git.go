@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const gitZeroSHA = "0000000000000000000000000000000000000000"
+
+// gitToplevel returns the root of the git repository containing dir
+func gitToplevel(dir string) (string, error) {
+	output, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("not a git repository (%s): %w", dir, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// GitChangedFiles lists the absolute paths of files added, copied, modified or renamed in the repository
+// containing dir, either staged in the index or changed since the given ref (committed or not)
+func GitChangedFiles(dir string, sinceRef string, staged bool) ([]string, error) {
+	toplevel, err := gitToplevel(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"diff", "--name-only", "-z", "--diff-filter=ACMR"}
+	switch {
+	case staged:
+		args = append(args, "--cached")
+	case sinceRef != "":
+		args = append(args, sinceRef)
+	default:
+		return nil, fmt.Errorf("either a ref or staged mode is required")
+	}
+
+	output, err := runGit(toplevel, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, name := range strings.Split(output, "\x00") {
+		if name == "" {
+			continue
+		}
+		files = append(files, filepath.Join(toplevel, filepath.FromSlash(name)))
+	}
+
+	// untracked files are new since any ref
+	if !staged {
+		untracked, err := runGit(toplevel, "ls-files", "--others", "--exclude-standard", "-z")
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range strings.Split(untracked, "\x00") {
+			if name == "" {
+				continue
+			}
+			files = append(files, filepath.Join(toplevel, filepath.FromSlash(name)))
+		}
+	}
+
+	return files, nil
+}
+
+// GitPushedFiles lists the absolute paths of files touched by the commits being pushed,
+// reading the "<local ref> <local sha> <remote ref> <remote sha>" lines git passes to pre-push hooks
+func GitPushedFiles(dir string, updates io.Reader) ([]string, error) {
+	toplevel, err := gitToplevel(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	files := []string{}
+
+	addNames := func(output string) {
+		for _, name := range strings.Split(output, "\x00") {
+			if name == "" {
+				continue
+			}
+			path := filepath.Join(toplevel, filepath.FromSlash(name))
+			if _, ok := seen[path]; !ok {
+				seen[path] = struct{}{}
+				files = append(files, path)
+			}
+		}
+	}
+
+	scanner := bufio.NewScanner(updates)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			continue
+		}
+		localSHA, remoteSHA := fields[1], fields[3]
+		if localSHA == gitZeroSHA {
+			continue // branch deletion
+		}
+
+		if remoteSHA != gitZeroSHA {
+			output, err := runGit(toplevel, "diff", "--name-only", "-z", "--diff-filter=ACMR", remoteSHA, localSHA)
+			if err != nil {
+				return nil, err
+			}
+			addNames(output)
+			continue
+		}
+
+		// new remote branch: every commit not yet on any remote
+		commits, err := runGit(toplevel, "rev-list", localSHA, "--not", "--remotes")
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range strings.Fields(commits) {
+			output, err := runGit(toplevel, "diff-tree", "--no-commit-id", "--name-only", "-r", "-z", "--diff-filter=ACMR", "--root", commit)
+			if err != nil {
+				return nil, err
+			}
+			addNames(output)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading pushed refs: %w", err)
+	}
+
+	return files, nil
+}
+
+// GitIndexSnapshot checks the staged content of files out of the index into a temporary directory laid
+// out like the repository and returns the directory matching dir, partially staged files are audited as
+// they will be committed
+func GitIndexSnapshot(dir string, files []string) (string, func(), error) {
+	noop := func() {}
+	toplevel, err := gitToplevel(dir)
+	if err != nil {
+		return "", noop, err
+	}
+	if resolved, err := filepath.EvalSymlinks(toplevel); err == nil {
+		toplevel = resolved
+	}
+
+	var names bytes.Buffer
+	for _, file := range files {
+		rel, err := repositoryRelPath(toplevel, file)
+		if err != nil {
+			return "", noop, err
+		}
+		names.WriteString(rel)
+		names.WriteByte(0)
+	}
+	relDir, err := repositoryRelPath(toplevel, dir)
+	if err != nil {
+		return "", noop, err
+	}
+
+	root, err := os.MkdirTemp("", "intercept-index-")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create index snapshot directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(root) }
+
+	cmd := exec.Command("git", "-C", toplevel, "checkout-index", "-z", "--stdin", "--prefix="+root+string(filepath.Separator))
+	cmd.Stdin = &names
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("git checkout-index: %w %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return filepath.Join(root, filepath.FromSlash(relDir)), cleanup, nil
+}
+
+// repositoryRelPath returns the slash separated path of a file relative to the repository root
+func repositoryRelPath(toplevel, file string) (string, error) {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = resolved
+	}
+	rel, err := filepath.Rel(toplevel, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of the repository %s", file, toplevel)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// FilterFilesByList keeps the target files that are part of the given list of paths
+func FilterFilesByList(fileInfos []FileInfo, paths []string) []FileInfo {
+	wanted := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		wanted[filepath.Clean(p)] = struct{}{}
+	}
+
+	var filteredFiles []FileInfo
+	for _, fi := range fileInfos {
+		absPath, err := filepath.Abs(fi.Path)
+		if err != nil {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+			absPath = resolved
+		}
+		if _, ok := wanted[absPath]; ok {
+			filteredFiles = append(filteredFiles, fi)
+		}
+	}
+	return filteredFiles
+}
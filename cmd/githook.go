@@ -0,0 +1,434 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	gitHookPreCommit = "pre-commit"
+	gitHookPrePush   = "pre-push"
+
+	// gitHookMarker identifies hook scripts managed by intercept
+	gitHookMarker = "# intercept-hook: managed by intercept hook install"
+
+	// gitHookBypassEnv skips the hook audit, the bypass and its reason are recorded in the compliance log
+	gitHookBypassEnv = "INTERCEPT_HOOK_BYPASS"
+
+	gitHookBypassRuleID = "intercept-hook-bypass"
+)
+
+var (
+	gitHookType  string
+	gitHookForce bool
+)
+
+var gitHookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Install and run git pre-commit / pre-push hooks auditing staged or pushed files",
+	Long:  `Manage git hooks that run the audit pipeline only over the files git reports as staged (pre-commit) or pushed (pre-push).`,
+}
+
+var gitHookInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install an intercept git hook in the current repository",
+	Long: `Install an intercept git hook in the current repository.
+
+Examples:
+  Audit staged files against a policy before each commit:
+  $ intercept hook install --type pre-commit --policy policies/secrets.yaml --tags-any secrets
+
+  Skip the hook once, the bypass is recorded in the compliance log:
+  $ INTERCEPT_HOOK_BYPASS="hotfix, reviewed by secops" git commit ...`,
+	Run: runGitHookInstall,
+}
+
+var gitHookUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove an intercept git hook from the current repository",
+	Run:   runGitHookUninstall,
+}
+
+var gitHookRunCmd = &cobra.Command{
+	Use:    "run",
+	Short:  "Run the audit for a git hook (invoked by the installed hook)",
+	Args:   cobra.ArbitraryArgs,
+	Hidden: true,
+	Run:    runGitHook,
+}
+
+func init() {
+	rootCmd.AddCommand(gitHookCmd)
+	gitHookCmd.AddCommand(gitHookInstallCmd, gitHookUninstallCmd, gitHookRunCmd)
+
+	gitHookCmd.PersistentFlags().StringVar(&gitHookType, "type", gitHookPreCommit, "Git hook type : pre-commit,pre-push")
+
+	for _, c := range []*cobra.Command{gitHookInstallCmd, gitHookRunCmd} {
+		c.Flags().StringArrayVarP(&policyFiles, "policy", "p", nil, "Policy <FILEPATH>, <DIRECTORY> or <URL>, repeat to layer overlays")
+		c.Flags().StringVar(&policyFileSHA256, "checksum", "", "Policy SHA256 expected checksum")
+		c.Flags().StringVarP(&tagsAny, "tags-any", "f", "", "Filter policies that match any of the provided tags (comma-separated)")
+		c.Flags().StringVar(&tagsAll, "tags-all", "", "Filter policies that match all of the provided tags (comma-separated)")
+		c.Flags().StringVarP(&environment, "environment", "e", "", "Filter policies that match the specified environment")
+		c.Flags().StringVar(&exceptionsFile, "exceptions", "", "Exceptions <FILEPATH> with per-finding suppressions")
+		c.Flags().StringVar(&baselineFile, "baseline", "", "Baseline SARIF <FILEPATH>, only new findings block the hook")
+		c.Flags().StringVar(&scanEngine, "engine", "", "Regex engine for scan and assure policies : native,ripgrep (default ripgrep)")
+		c.Flags().StringVar(&failOn, "fail-on", "", "Minimum SARIF level that blocks the hook : error,warning,never (default warning)")
+	}
+	gitHookRunCmd.Flags().StringVarP(&targetDir, "target", "t", "", "Target directory to audit (default repository root)")
+	gitHookInstallCmd.Flags().BoolVar(&gitHookForce, "force", false, "Overwrite an existing hook not managed by intercept")
+}
+
+func validateGitHookType() {
+	if gitHookType != gitHookPreCommit && gitHookType != gitHookPrePush {
+		log.Fatal().Str("type", gitHookType).Msg("Unsupported hook type (expected pre-commit or pre-push)")
+	}
+}
+
+// gitHookPath returns the path of the hook script, honouring core.hooksPath
+func gitHookPath(hookType string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	toplevel, err := gitToplevel(cwd)
+	if err != nil {
+		return "", err
+	}
+	hooksDir, err := runGit(toplevel, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	hooksDir = strings.TrimSpace(hooksDir)
+	if !filepath.IsAbs(hooksDir) {
+		hooksDir = filepath.Join(toplevel, hooksDir)
+	}
+	return filepath.Join(hooksDir, hookType), nil
+}
+
+func runGitHookInstall(cmd *cobra.Command, args []string) {
+	validateGitHookType()
+
+	if len(policyFiles) == 0 {
+		log.Fatal().Msg("A policy is required to install a hook")
+	}
+
+	hookPath, err := gitHookPath(gitHookType)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error locating git hooks directory")
+	}
+
+	if existing, err := os.ReadFile(hookPath); err == nil && !strings.Contains(string(existing), gitHookMarker) && !gitHookForce {
+		log.Fatal().Str("hook", hookPath).Msg("A hook not managed by intercept already exists, use --force to overwrite it")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error locating the intercept executable")
+	}
+
+	runArgs := []string{"hook", "run", "--type", gitHookType}
+	for _, policy := range policyFiles {
+		// hooks run from the repository root, keep local paths working from anywhere
+		if !strings.HasPrefix(policy, "http://") && !strings.HasPrefix(policy, "https://") {
+			if policy, err = filepath.Abs(policy); err != nil {
+				log.Fatal().Err(err).Msg("Error resolving policy path")
+			}
+		}
+		runArgs = append(runArgs, "--policy", policy)
+	}
+	for _, flag := range []struct{ name, value string }{
+		{"checksum", policyFileSHA256},
+		{"tags-any", tagsAny},
+		{"tags-all", tagsAll},
+		{"environment", environment},
+		{"exceptions", absPathOrEmpty(exceptionsFile)},
+		{"baseline", absPathOrEmpty(baselineFile)},
+		{"fail-on", failOn},
+		{"engine", scanEngine},
+		{"output-type", flagValueIfChanged(cmd, "output-type")},
+		{"log-type", flagValueIfChanged(cmd, "log-type")},
+	} {
+		if flag.value != "" {
+			runArgs = append(runArgs, "--"+flag.name, flag.value)
+		}
+	}
+
+	quoted := make([]string, 0, len(runArgs)+1)
+	quoted = append(quoted, shellQuote(executable))
+	for _, arg := range runArgs {
+		quoted = append(quoted, shellQuote(arg))
+	}
+
+	script := strings.Join([]string{
+		"#!/bin/sh",
+		gitHookMarker,
+		fmt.Sprintf("# skip once with %s=\"<reason>\", the bypass is recorded in the compliance log", gitHookBypassEnv),
+		fmt.Sprintf("exec %s -o \"$(git rev-parse --git-dir)/intercept\" \"$@\"", strings.Join(quoted, " ")),
+		"",
+	}, "\n")
+
+	if err := os.MkdirAll(filepath.Dir(hookPath), 0755); err != nil {
+		log.Fatal().Err(err).Msg("Error creating git hooks directory")
+	}
+	if err := os.WriteFile(hookPath, []byte(script), 0755); err != nil {
+		log.Fatal().Err(err).Str("hook", hookPath).Msg("Error writing git hook")
+	}
+
+	log.Log().Msgf("Installed %s hook at %s", gitHookType, hookPath)
+}
+
+func runGitHookUninstall(cmd *cobra.Command, args []string) {
+	validateGitHookType()
+
+	hookPath, err := gitHookPath(gitHookType)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error locating git hooks directory")
+	}
+
+	existing, err := os.ReadFile(hookPath)
+	if os.IsNotExist(err) {
+		log.Log().Msgf("No %s hook installed", gitHookType)
+		return
+	} else if err != nil {
+		log.Fatal().Err(err).Str("hook", hookPath).Msg("Error reading git hook")
+	}
+
+	if !strings.Contains(string(existing), gitHookMarker) {
+		log.Fatal().Str("hook", hookPath).Msg("Hook is not managed by intercept, leaving it untouched")
+	}
+
+	if err := os.Remove(hookPath); err != nil {
+		log.Fatal().Err(err).Str("hook", hookPath).Msg("Error removing git hook")
+	}
+
+	log.Log().Msgf("Removed %s hook at %s", gitHookType, hookPath)
+}
+
+func runGitHook(cmd *cobra.Command, args []string) {
+	validateGitHookType()
+
+	perf := Performance{StartTime: time.Now()}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error reading working directory")
+	}
+	toplevel, err := gitToplevel(cwd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Hook must run inside a git repository")
+	}
+	if targetDir == "" {
+		targetDir = toplevel
+	}
+
+	// checked before the audit config is loaded, a broken or unreachable policy must not block the bypass
+	if reason, ok := os.LookupEnv(gitHookBypassEnv); ok {
+		prepareGitHookBypass()
+		defer cleanupSARIFProcessing()
+		recordGitHookBypass(toplevel, reason, perf)
+		return
+	}
+
+	config := prepareAudit()
+	defer cleanupSARIFProcessing()
+
+	if failOn == "" {
+		failOn = config.Flags.FailOn
+	}
+	failOnThreshold, err := parseFailOn(failOn)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid fail-on threshold")
+	}
+
+	if baselineFile == "" {
+		baselineFile = config.Flags.Baseline
+	}
+	if err := LoadBaseline(baselineFile); err != nil {
+		log.Fatal().Err(err).Str("file", baselineFile).Msg("Error loading baseline")
+	}
+
+	switch gitHookType {
+	case gitHookPreCommit:
+		stagedOnly = true
+	case gitHookPrePush:
+		pushedFiles, err = GitPushedFiles(toplevel, os.Stdin)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error listing pushed files from git")
+		}
+	}
+
+	mergedReport, err := executeAudit(config, &perf)
+	if err != nil {
+		fmt.Printf("\nINTERCEPT %s hook blocked: %v\n", gitHookType, err)
+		cleanupSARIFProcessing()
+		os.Exit(ExitCodeCritical)
+	}
+
+	worstLevel := WorstSARIFLevel(mergedReport)
+	exitCode := exitCodeForLevel(worstLevel, failOnThreshold)
+
+	printGitHookFindings(mergedReport, toplevel)
+
+	if exitCode != ExitCodeClean {
+		fmt.Printf("\nINTERCEPT %s hook blocked (worst level: %s)\n", gitHookType, sarifLevelToString(worstLevel))
+		fmt.Printf("Fix the findings above or skip once with %s=\"<reason>\" (recorded in the compliance log)\n", gitHookBypassEnv)
+		if exitMsg := exitMessageForLevel(config, worstLevel); exitMsg != "" {
+			fmt.Println(exitMsg)
+		}
+		cleanupSARIFProcessing()
+		os.Exit(exitCode)
+	}
+}
+
+// printGitHookFindings writes the active findings of a report as human readable text
+func printGitHookFindings(sarifReport SARIFReport, toplevel string) {
+	count := 0
+	for _, run := range sarifReport.Runs {
+		for _, result := range run.Results {
+			if result.Level != SARIFError && result.Level != SARIFWarning {
+				continue
+			}
+			if isSuppressed(result) || isBaselineFinding(result) {
+				continue
+			}
+			count++
+
+			location := ""
+			if len(result.Locations) > 0 {
+				physical := result.Locations[0].PhysicalLocation
+				location = physical.ArtifactLocation.URI
+				if rel, err := filepath.Rel(toplevel, location); err == nil && !strings.HasPrefix(rel, "..") {
+					location = rel
+				}
+				if physical.Region.StartLine > 0 {
+					location = fmt.Sprintf("%s:%d", location, physical.Region.StartLine)
+				}
+			}
+
+			fmt.Printf("[%s] %s %s\n", strings.ToUpper(string(result.Level)), result.RuleID, location)
+			if result.Message.Text != "" {
+				fmt.Printf("    %s\n", result.Message.Text)
+			}
+			if solution := gitHookSolution(result); solution != "" {
+				fmt.Printf("    solution: %s\n", solution)
+			}
+		}
+	}
+
+	if count == 0 {
+		fmt.Printf("INTERCEPT %s: no findings\n", gitHookType)
+	}
+}
+
+// gitHookSolution returns the msg_solution of a result, falling back to the metadata of its policy
+func gitHookSolution(result Result) string {
+	if result.Properties.MsgSolution != "" {
+		return result.Properties.MsgSolution
+	}
+	for _, policy := range GetPolicies() {
+		if policy.ID == result.RuleID || NormalizePolicyName(policy.ID) == result.RuleID {
+			return policy.Metadata.MsgSolution
+		}
+	}
+	return ""
+}
+
+// prepareGitHookBypass sets up the outputs of a bypassed hook like prepareAudit but never exits, policies
+// that can't be loaded only lose their config (output types, SARIF rules) in the recorded bypass
+func prepareGitHookBypass() {
+	loaded, err := LoadPolicySources(policyFiles, policyFileSHA256)
+	if err != nil {
+		log.Warn().Err(err).Strs("policy", policyFiles).Msg("Error loading policy file, recording the hook bypass without it")
+		loaded = &PolicyFile{}
+	}
+	policyData = loaded
+	if outputType != "" {
+		policyData.Config.Flags.OutputType = strings.Split(outputType, ",")
+	}
+
+	if err := cleanupOutputDirectories(); err != nil {
+		log.Warn().Err(err).Msg("Failed to clean up output directories")
+	}
+	if err := initSARIFProcessing(); err != nil {
+		log.Warn().Err(err).Msg("Failed to initialize SARIF processing")
+	}
+	if err := createOutputDirectories(false); err != nil {
+		log.Warn().Err(err).Msg("Failed to create output directories")
+	}
+}
+
+// recordGitHookBypass reports a skipped hook as a warning result so the bypass shows up in SARIF and the compliance log
+func recordGitHookBypass(toplevel, reason string, perf Performance) {
+	user, _ := runGit(toplevel, "config", "user.email")
+	user = strings.TrimSpace(user)
+	if reason == "" {
+		reason = "no reason given"
+	}
+
+	result := Result{
+		RuleID: gitHookBypassRuleID,
+		Level:  SARIFWarning,
+		Message: Message{
+			Text: fmt.Sprintf("%s hook bypassed by %s: %s", gitHookType, user, reason),
+		},
+		Locations: []Location{
+			{
+				PhysicalLocation: PhysicalLocation{
+					ArtifactLocation: ArtifactLocation{URI: toplevel},
+				},
+			},
+		},
+		Properties: ResultProperties{
+			ResultType:      "hook-bypass",
+			ResultTimestamp: time.Now().Format(time.RFC3339),
+			Environment:     environment,
+			Name:            gitHookBypassRuleID,
+			Description:     fmt.Sprintf("%s skipped with %s", gitHookType, gitHookBypassEnv),
+		},
+	}
+
+	if !outputTypeMatrixConfig.LOG {
+		log.Warn().Msg("Compliance log output is disabled, the hook bypass is only recorded in the SARIF report")
+	}
+
+	report := createSARIFReport([]Result{result})
+	if err := writeSARIFReport(gitHookBypassRuleID, report); err != nil {
+		log.Error().Err(err).Msg("Error writing hook bypass report")
+	}
+
+	perf.EndTime = time.Now()
+	perf.Delta = perf.EndTime.Sub(perf.StartTime)
+	if _, err := MergeSARIFReports(strings.Join(os.Args, " "), perf, false); err != nil {
+		log.Debug().Err(err).Msg("Failed to merge SARIF reports")
+	}
+
+	log.Warn().Str("hook", gitHookType).Str("user", user).Str("reason", reason).Msg("Hook bypassed")
+	fmt.Printf("INTERCEPT %s hook bypassed (%s), the bypass has been recorded\n", gitHookType, reason)
+}
+
+func absPathOrEmpty(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func flagValueIfChanged(cmd *cobra.Command, name string) string {
+	if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+		return flag.Value.String()
+	}
+	return ""
+}
+
+// shellQuote quotes a value for a POSIX shell script
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
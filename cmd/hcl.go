@@ -0,0 +1,418 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// hclExtensions are checked by hcl policies without filepattern or include
+var hclExtensions = []string{".tf", ".tfvars", ".hcl"}
+
+// hclLocation is the source range of a field of the tree, blocks and top level attributes also carry
+// their address (aws_s3_bucket.logs, module.vpc, var.region) and SARIF logical location kind
+type hclLocation struct {
+	line         int
+	endLine      int
+	address      string
+	resourceType string
+	kind         string
+}
+
+// hclTree is the JSON-like value of an HCL file : attributes are fields, labeled blocks are nested
+// under their type and labels (resource.aws_s3_bucket.logs) and unlabeled blocks are lists. Provider
+// blocks are lists under their name (provider.aws[0]) as aliases repeat it, other labeled blocks
+// repeating the labels of a previous block are reported as duplicates
+type hclTree struct {
+	src       []byte
+	value     map[string]interface{}
+	locations map[string]hclLocation
+	addresses map[string]hclLocation
+	diags     hcl.Diagnostics
+}
+
+// hclViolation is a failed check located on an HCL range
+type hclViolation struct {
+	file         string
+	line         int
+	endLine      int
+	message      string
+	address      string
+	resourceType string
+	kind         string
+}
+
+// ProcessHCLType handles policies of type "hcl"
+func ProcessHCLType(policy Policy, targetDir string, filePaths []string) error {
+	if policy.Type != "hcl" {
+		return nil
+	}
+
+	violations, err := executeHCL(policy, filePaths)
+	if err != nil {
+		log.Error().Err(err).Str("policy", policy.ID).Msg("Error evaluating hcl policy")
+		return fmt.Errorf("error evaluating hcl policy %s: %w", policy.ID, err)
+	}
+	sarifReport := GenerateHCLSARIFReport(policy, violations)
+
+	reportID := policy.ID
+	if policy.RunID != "" {
+		reportID = policy.RunID
+	}
+	if err := writeSARIFReport(reportID, sarifReport); err != nil {
+		log.Error().Err(err).Msg("error writing SARIF report")
+		return fmt.Errorf("error writing SARIF report: %w", err)
+	}
+
+	log.Debug().Msgf("Policy %s processed. %d hcl violations found", policy.ID, len(violations))
+	return nil
+}
+
+func executeHCL(policy Policy, filePaths []string) ([]hclViolation, error) {
+	evaluate, err := compileStructuredEvaluator(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// without filepattern or include the policy picks the Terraform and HCL files of the target
+	byExtension := policy.FilePattern == "" && len(policy.Include) == 0
+
+	var violations []hclViolation
+	for _, file := range filePaths {
+		if byExtension && !containsString(hclExtensions, strings.ToLower(filepath.Ext(file))) {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Debug().Err(err).Str("file", file).Msg("hcl skipped unreadable file")
+			continue
+		}
+
+		tree, diags := parseHCLTree(content, file)
+		if diags.HasErrors() {
+			for _, diag := range diags.Errs() {
+				violation := hclViolation{file: file, line: 1, endLine: 1, message: fmt.Sprintf("invalid HCL: %v", diag)}
+				if d, ok := diag.(*hcl.Diagnostic); ok && d.Subject != nil {
+					violation.line, violation.endLine = d.Subject.Start.Line, d.Subject.End.Line
+					violation.message = fmt.Sprintf("invalid HCL: %s: %s", d.Summary, d.Detail)
+				}
+				violations = append(violations, violation)
+			}
+			continue
+		}
+
+		input, err := structuredInput(tree.value)
+		if err != nil {
+			return nil, err
+		}
+		found, err := evaluate(input)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, found := range found {
+			violation := hclViolation{file: file, message: found.message}
+			var location hclLocation
+			switch {
+			case found.path != nil:
+				location = tree.locate(found.path)
+			case found.address != "":
+				location = tree.addresses[found.address]
+				location.address = found.address
+			case found.line > 0:
+				location = tree.locateLine(found.line)
+				location.line, location.endLine = found.line, found.endLine
+			}
+			violation.line, violation.endLine = location.line, location.endLine
+			violation.address, violation.resourceType, violation.kind = location.address, location.resourceType, location.kind
+			if violation.line < 1 {
+				violation.line = 1
+			}
+			if violation.endLine < violation.line {
+				violation.endLine = violation.line
+			}
+			violations = append(violations, violation)
+		}
+	}
+	return violations, nil
+}
+
+// parseHCLTree parses an HCL file and converts its body, literal values are evaluated and other
+// expressions (references, function calls) are kept as "${...}" strings like the Terraform JSON syntax
+func parseHCLTree(content []byte, file string) (*hclTree, hcl.Diagnostics) {
+	parsed, diags := hclsyntax.ParseConfig(content, file, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body, ok := parsed.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "unsupported HCL body"}}
+	}
+
+	tree := &hclTree{src: content, locations: make(map[string]hclLocation), addresses: make(map[string]hclLocation)}
+	tree.value = tree.body(body, nil)
+	return tree, append(diags, tree.diags...)
+}
+
+func (t *hclTree) body(body *hclsyntax.Body, path []string) map[string]interface{} {
+	value := make(map[string]interface{})
+
+	for name, attribute := range body.Attributes {
+		attributePath := appendPath(path, name)
+		location := rangeLocation(attribute.SrcRange)
+		if len(path) == 0 || (len(path) == 2 && path[0] == "locals") {
+			location.address, location.kind = hclAttributeAddress(path, name), "variable"
+			t.addresses[location.address] = location
+		}
+		t.locations[hclPathKey(attributePath)] = location
+		value[name] = t.expression(attribute.Expr, attributePath)
+	}
+
+	blocks := append([]*hclsyntax.Block(nil), body.Blocks...)
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].TypeRange.Start.Byte < blocks[j].TypeRange.Start.Byte })
+	for _, block := range blocks {
+		blockPath := appendPath(path, block.Type)
+		switch {
+		case len(block.Labels) == 0:
+			list, _ := value[block.Type].([]interface{})
+			blockPath = append(blockPath, strconv.Itoa(len(list)))
+			value[block.Type] = append(list, t.body(block.Body, blockPath))
+		case len(path) == 0 && block.Type == "provider" && len(block.Labels) == 1:
+			providers, _ := value[block.Type].(map[string]interface{})
+			if providers == nil {
+				providers = make(map[string]interface{})
+				value[block.Type] = providers
+			}
+			list, _ := providers[block.Labels[0]].([]interface{})
+			blockPath = append(blockPath, block.Labels[0], strconv.Itoa(len(list)))
+			providers[block.Labels[0]] = append(list, t.body(block.Body, blockPath))
+		default:
+			parent := value
+			for i, label := range block.Labels {
+				blockPath = append(blockPath, label)
+				key := block.Type
+				if i > 0 {
+					key = block.Labels[i-1]
+				}
+				child, _ := parent[key].(map[string]interface{})
+				if child == nil {
+					child = make(map[string]interface{})
+					parent[key] = child
+				}
+				parent = child
+			}
+			last := block.Labels[len(block.Labels)-1]
+			if _, exists := parent[last]; exists {
+				t.diags = append(t.diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate block",
+					Detail:   fmt.Sprintf("a %s block labeled \"%s\" is already defined", block.Type, strings.Join(block.Labels, `" "`)),
+					Subject:  block.DefRange().Ptr(),
+				})
+				continue
+			}
+			parent[last] = t.body(block.Body, blockPath)
+		}
+
+		location := rangeLocation(hcl.RangeBetween(block.TypeRange, block.Body.SrcRange))
+		if len(path) == 0 {
+			location.address, location.resourceType, location.kind = hclBlockAddress(block)
+			t.addresses[location.address] = location
+		}
+		t.locations[hclPathKey(blockPath)] = location
+	}
+	return value
+}
+
+func (t *hclTree) expression(expr hclsyntax.Expression, path []string) interface{} {
+	switch e := expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		value := make(map[string]interface{})
+		for _, item := range e.Items {
+			key := t.source(item.KeyExpr.Range())
+			if keyValue, diags := item.KeyExpr.Value(nil); !diags.HasErrors() && keyValue.IsKnown() && keyValue.Type().FriendlyName() == "string" {
+				key = keyValue.AsString()
+			}
+			itemPath := appendPath(path, key)
+			t.locations[hclPathKey(itemPath)] = rangeLocation(hcl.RangeBetween(item.KeyExpr.Range(), item.ValueExpr.Range()))
+			value[key] = t.expression(item.ValueExpr, itemPath)
+		}
+		return value
+	case *hclsyntax.TupleConsExpr:
+		value := make([]interface{}, len(e.Exprs))
+		for i, item := range e.Exprs {
+			itemPath := appendPath(path, strconv.Itoa(i))
+			t.locations[hclPathKey(itemPath)] = rangeLocation(item.Range())
+			value[i] = t.expression(item, itemPath)
+		}
+		return value
+	}
+
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsWhollyKnown() {
+		source := t.source(expr.Range())
+		if _, ok := expr.(*hclsyntax.TemplateExpr); ok && strings.HasPrefix(source, `"`) {
+			// quoted templates already are interpolation strings
+			return strings.TrimSuffix(strings.TrimPrefix(source, `"`), `"`)
+		}
+		return "${" + source + "}"
+	}
+	data, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return "${" + t.source(expr.Range()) + "}"
+	}
+	var converted interface{}
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return "${" + t.source(expr.Range()) + "}"
+	}
+	return converted
+}
+
+func (t *hclTree) source(r hcl.Range) string {
+	if r.Start.Byte < 0 || r.End.Byte > len(t.src) || r.Start.Byte > r.End.Byte {
+		return ""
+	}
+	return string(r.SliceBytes(t.src))
+}
+
+// locate returns the range of the deepest field of a path found in the tree, and the address of
+// the top level block or attribute holding it
+func (t *hclTree) locate(path []string) hclLocation {
+	var location hclLocation
+	for i := len(path); i > 0; i-- {
+		found, ok := t.locations[hclPathKey(path[:i])]
+		if !ok {
+			continue
+		}
+		if location.line == 0 {
+			location.line, location.endLine = found.line, found.endLine
+		}
+		if found.address != "" {
+			location.address, location.resourceType, location.kind = found.address, found.resourceType, found.kind
+			break
+		}
+	}
+	return location
+}
+
+// locateLine returns the address of the top level block or attribute holding a line
+func (t *hclTree) locateLine(line int) hclLocation {
+	for _, location := range t.addresses {
+		if line >= location.line && line <= location.endLine {
+			return location
+		}
+	}
+	return hclLocation{}
+}
+
+// hclBlockAddress follows the Terraform addressing of top level blocks, other HCL blocks are
+// addressed by their type and labels
+func hclBlockAddress(block *hclsyntax.Block) (string, string, string) {
+	labels := block.Labels
+	switch {
+	case block.Type == "resource" && len(labels) == 2:
+		return labels[0] + "." + labels[1], labels[0], "resource"
+	case block.Type == "data" && len(labels) == 2:
+		return "data." + labels[0] + "." + labels[1], labels[0], "resource"
+	case block.Type == "variable" && len(labels) == 1:
+		return "var." + labels[0], block.Type, "variable"
+	case block.Type == "provider" && len(labels) == 1:
+		address := block.Type + "." + labels[0]
+		if alias, ok := block.Body.Attributes["alias"]; ok {
+			if value, diags := alias.Expr.Value(nil); !diags.HasErrors() && value.IsKnown() && value.Type() == cty.String {
+				address += "." + value.AsString()
+			}
+		}
+		return address, block.Type, "object"
+	case block.Type == "module":
+		return strings.Join(append([]string{block.Type}, labels...), "."), block.Type, "module"
+	}
+	return strings.Join(append([]string{block.Type}, labels...), "."), block.Type, "object"
+}
+
+// hclAttributeAddress addresses top level attributes (tfvars) by name and locals as local.<name>
+func hclAttributeAddress(path []string, name string) string {
+	if len(path) > 0 {
+		return "local." + name
+	}
+	return name
+}
+
+func rangeLocation(r hcl.Range) hclLocation {
+	return hclLocation{line: r.Start.Line, endLine: r.End.Line}
+}
+
+func appendPath(path []string, elements ...string) []string {
+	return append(append([]string(nil), path...), elements...)
+}
+
+func hclPathKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+func GenerateHCLSARIFReport(policy Policy, violations []hclViolation) SARIFReport {
+	sarifReport := newPolicySARIFReport(policy)
+
+	properties := policyResultProperties(policy)
+
+	if len(violations) == 0 {
+		sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, compliantResult(policy, "No HCL violations found"))
+	}
+
+	level := calculateSARIFLevel(policy, environment)
+	sources := sourceLines{}
+	for _, violation := range violations {
+		message := fmt.Sprintf("Policy violation: %s %s", policy.Metadata.Name, violation.message)
+		if violation.address != "" {
+			message = fmt.Sprintf("Policy violation: %s %s: %s", policy.Metadata.Name, violation.address, violation.message)
+		}
+
+		region := Region{StartLine: violation.line, StartColumn: 1, EndLine: violation.endLine, EndColumn: 1}
+		if lines := sources.get(violation.file); violation.line <= len(lines) {
+			line := strings.TrimRight(lines[violation.line-1], "\r\n")
+			if violation.endLine == violation.line {
+				region.EndColumn = len(line) + 1
+			}
+			region.Snippet = Snippet{Text: strings.TrimSpace(line)}
+		}
+
+		location := Location{PhysicalLocation: PhysicalLocation{
+			ArtifactLocation: ArtifactLocation{URI: filepath.ToSlash(violation.file)},
+			Region:           region,
+			ContextRegion:    sources.contextRegion(violation.file, region, policy.ContextLines),
+		}}
+		if violation.address != "" {
+			location.LogicalLocations = []LogicalLocation{{Name: violation.address, FullyQualifiedName: violation.address, Kind: violation.kind}}
+		}
+
+		result := Result{
+			RuleID:     policy.ID,
+			Level:      level,
+			Message:    Message{Text: message},
+			Locations:  []Location{location},
+			Properties: properties,
+		}
+		result.Properties.SarifInt = sarifLevelToInt(level)
+		result.Properties.ResourceType = violation.resourceType
+		result.Properties.ResourceAddress = violation.address
+
+		sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, result)
+	}
+
+	sarifReport.Runs[0].Results = applyExceptions(virtualizeResultURIs(sarifReport.Runs[0].Results))
+	sarifReport.Runs[0].Invocations[0].Properties.ReportCompliant = ComplianceStatus(sarifReport)
+
+	if outputTypeMatrixConfig.LOG {
+		PostResultsToComplianceLog(sarifReport)
+	}
+
+	return sarifReport
+}
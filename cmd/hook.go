@@ -123,6 +123,9 @@ func calculateWebhookSignature(payload []byte, secret string) (string, error) {
 
 func PostResultsToWebhooks(sarifReport SARIFReport) error {
 
+	// findings already known in the baseline are not posted
+	sarifReport = filterNewFindings(sarifReport)
+
 	timestamp := time.Now().Format(time.RFC3339)
 
 	if len(sarifReport.Runs) == 0 {
@@ -269,6 +272,9 @@ func PostResultsToWebhooks(sarifReport SARIFReport) error {
 
 func PostReportToWebhooks(sarifReport SARIFReport) error {
 
+	// findings already known in the baseline are not posted
+	sarifReport = filterNewFindings(sarifReport)
+
 	timestamp := time.Now().Format(time.RFC3339)
 
 	if len(sarifReport.Runs) == 0 {
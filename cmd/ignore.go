@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ignoreFileNames are read in every directory of the target, deeper files take precedence
+var ignoreFileNames = []string{".gitignore", ".interceptignore"}
+
+// ignoreRule is one gitignore pattern, matched against paths relative to the directory it was declared in
+type ignoreRule struct {
+	base    string
+	pattern string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher implements gitignore semantics for the target walk and the scan engines :
+// the ignore files of each directory (loaded on first use) then Config.Flags.ignore, the last matching rule wins
+type ignoreMatcher struct {
+	root  string
+	rules []ignoreRule
+
+	mu   sync.RWMutex
+	dirs map[string][]ignoreRule
+}
+
+func newIgnoreMatcher(root string, patterns []string) (*ignoreMatcher, error) {
+	rules, err := compileIgnoreRules("", patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &ignoreMatcher{root: root, rules: rules, dirs: make(map[string][]ignoreRule)}, nil
+}
+
+// compileIgnoreRules parses gitignore lines, blank lines and comments are skipped
+func compileIgnoreRules(base string, patterns []string) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	for _, pattern := range patterns {
+		rule, ok, err := parseIgnorePattern(base, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func parseIgnorePattern(base, line string) (ignoreRule, bool, error) {
+	rule := ignoreRule{base: base, pattern: line}
+
+	pattern := strings.TrimRight(line, " \t\r")
+	if strings.HasSuffix(pattern, `\`) && strings.HasSuffix(line, " ") {
+		pattern += " "
+	}
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return rule, false, nil
+	}
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\!`) || strings.HasPrefix(pattern, `\#`) {
+		pattern = pattern[1:]
+	}
+	pattern = filepath.ToSlash(pattern)
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return rule, false, nil
+	}
+
+	// a slash at the start or in the middle anchors the pattern to its directory
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	expr, err := ignorePatternRegexp(pattern)
+	if err != nil {
+		return rule, false, fmt.Errorf("invalid ignore pattern %q: %w", line, err)
+	}
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	rule.re, err = regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule, false, fmt.Errorf("invalid ignore pattern %q: %w", line, err)
+	}
+	return rule, true, nil
+}
+
+// ignorePatternRegexp translates the wildcards of a gitignore pattern, ** spans directories
+func ignorePatternRegexp(pattern string) (string, error) {
+	var expr strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case pattern[i:] == "**" && (i == 0 || pattern[i-1] == '/'):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expr.String(), nil
+}
+
+// matchIgnoreRules applies rules in order to a slash separated path, the last matching rule decides
+func matchIgnoreRules(rules []ignoreRule, rel string, isDir bool, ignored bool) bool {
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			sub = rel[len(rule.base)+1:]
+		}
+		if rule.re.MatchString(sub) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// dirRules returns the rules of the ignore files of a directory relative to the root
+func (m *ignoreMatcher) dirRules(dir string) []ignoreRule {
+	m.mu.RLock()
+	rules, ok := m.dirs[dir]
+	m.mu.RUnlock()
+	if ok {
+		return rules
+	}
+
+	for _, name := range ignoreFileNames {
+		file := filepath.Join(m.root, filepath.FromSlash(dir), name)
+		lines, err := readIgnoreFile(file)
+		if err != nil {
+			continue
+		}
+		base := dir
+		if base == "." {
+			base = ""
+		}
+		fileRules, err := compileIgnoreRules(base, lines)
+		if err != nil {
+			log.Warn().Err(err).Str("file", file).Msg("skipping invalid ignore file")
+			continue
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	m.mu.Lock()
+	m.dirs[dir] = rules
+	m.mu.Unlock()
+	return rules
+}
+
+func readIgnoreFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// matchPath reports whether a path relative to the root is ignored, ignoring its parent directories only
+func (m *ignoreMatcher) matchPath(rel string, isDir bool) bool {
+	var ignored bool
+	dir := path.Dir(rel)
+	var parents []string
+	for d := dir; d != "." && d != "/"; d = path.Dir(d) {
+		parents = append(parents, d)
+	}
+	ignored = matchIgnoreRules(m.dirRules("."), rel, isDir, ignored)
+	for i := len(parents) - 1; i >= 0; i-- {
+		ignored = matchIgnoreRules(m.dirRules(parents[i]), rel, isDir, ignored)
+	}
+	return matchIgnoreRules(m.rules, rel, isDir, ignored)
+}
+
+// matchPathOrParents reports whether a file is ignored itself or through one of its directories, like the
+// target walk that skips ignored directories
+func (m *ignoreMatcher) matchPathOrParents(rel string) bool {
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchPath(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matchPath(rel, false)
+}
+
+// targetRelPath returns the slash separated path of p relative to root, false outside of it
+func targetRelPath(root, p string) (string, bool) {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return rel, true
+}
+
+// policyIgnored reports whether a target file is excluded by the ignore list of a policy
+func policyIgnored(rules []ignoreRule, targetDir, p string) bool {
+	if len(rules) == 0 {
+		return false
+	}
+	rel, ok := targetRelPath(targetDir, p)
+	if !ok {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if matchIgnoreRules(rules, strings.Join(parts[:i], "/"), true, false) {
+			return true
+		}
+	}
+	return matchIgnoreRules(rules, rel, false, false)
+}
@@ -0,0 +1,458 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	licenseAllowed = "allowed"
+	licenseReview  = "review"
+	licenseDenied  = "denied"
+
+	licenseSourceSPDX     = "spdx-identifier"
+	licenseSourceFile     = "license-file"
+	licenseSourceHeader   = "header-text"
+	licenseSourceManifest = "manifest"
+
+	licenseNoAssertion        = "NOASSERTION"
+	licenseDefaultHeaderLines = 30
+)
+
+var licenseUnknownModes = []string{"allow", "review", "deny"}
+
+// licenseFingerprints identify license texts. Texts of named licenses quote other licenses (GPL-3.0 mentions the AGPL,
+// MPL-2.0 lists the GNU licenses), the license named first in the text is the one checked, its versions in this order.
+var licenseFingerprints = []struct {
+	id      string
+	name    string
+	phrases []string
+}{
+	{"AGPL-3.0", "GNU AFFERO GENERAL PUBLIC LICENSE", []string{"Version 3"}},
+	{"LGPL-3.0", "GNU LESSER GENERAL PUBLIC LICENSE", []string{"Version 3"}},
+	{"LGPL-2.1", "GNU LESSER GENERAL PUBLIC LICENSE", []string{"Version 2.1"}},
+	{"GPL-3.0", "GNU GENERAL PUBLIC LICENSE", []string{"Version 3"}},
+	{"GPL-2.0", "GNU GENERAL PUBLIC LICENSE", []string{"Version 2"}},
+	{"MPL-2.0", "Mozilla Public License", []string{"2.0"}},
+	{"EPL-2.0", "Eclipse Public License", []string{"2.0"}},
+	{"Apache-2.0", "Apache License", []string{"Version 2.0"}},
+	{"CC0-1.0", "CC0 1.0 Universal", nil},
+	{"BSD-3-Clause", "", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", "", []string{"Redistribution and use in source and binary forms"}},
+	{"ISC", "", []string{"Permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"MIT", "", []string{"Permission is hereby granted, free of charge, to any person obtaining a copy"}},
+	{"Unlicense", "", []string{"This is free and unencumbered software released into the public domain"}},
+}
+
+// licenseAliases map the license names found in manifests (pom.xml) to SPDX identifiers
+var licenseAliases = map[string]string{
+	"apache license, version 2.0":              "Apache-2.0",
+	"apache license 2.0":                       "Apache-2.0",
+	"the apache software license, version 2.0": "Apache-2.0",
+	"apache 2.0":                               "Apache-2.0",
+	"mit license":                              "MIT",
+	"the mit license":                          "MIT",
+	"bsd 3-clause":                             "BSD-3-Clause",
+	"new bsd license":                          "BSD-3-Clause",
+	"bsd 2-clause":                             "BSD-2-Clause",
+	"simplified bsd license":                   "BSD-2-Clause",
+	"eclipse public license 2.0":               "EPL-2.0",
+	"mozilla public license 2.0":               "MPL-2.0",
+	"gnu general public license v3.0":          "GPL-3.0",
+	"gnu general public license v2.0":          "GPL-2.0",
+	"gnu lesser general public license v3.0":   "LGPL-3.0",
+}
+
+var (
+	spdxIdentifierLine   = regexp.MustCompile(`SPDX-License-Identifier:\s*(.+)`)
+	tomlLicenseLine      = regexp.MustCompile(`^\s*license\s*=\s*(?:\{\s*text\s*=\s*)?"([^"]+)"`)
+	licenseFileName      = regexp.MustCompile(`(?i)^(?:licen[cs]e|copying|unlicense)(?:[-._].*)?$`)
+	licenseFingerprintRe = compileLicenseFingerprints()
+)
+
+// licenseFinding is a license detected in a target file
+type licenseFinding struct {
+	file    string
+	line    int
+	license string
+	source  string
+	status  string
+}
+
+type licenseFingerprint struct {
+	name    *regexp.Regexp
+	phrases []*regexp.Regexp
+}
+
+func compileLicenseFingerprints() []licenseFingerprint {
+	compiled := make([]licenseFingerprint, len(licenseFingerprints))
+	for i, fingerprint := range licenseFingerprints {
+		if fingerprint.name != "" {
+			compiled[i].name = compileLicensePhrase(fingerprint.name)
+		}
+		for _, phrase := range fingerprint.phrases {
+			compiled[i].phrases = append(compiled[i].phrases, compileLicensePhrase(phrase))
+		}
+	}
+	return compiled
+}
+
+// compileLicensePhrase matches a phrase case-insensitively across line breaks and comment markers
+func compileLicensePhrase(phrase string) *regexp.Regexp {
+	words := strings.Fields(regexp.QuoteMeta(phrase))
+	return regexp.MustCompile(`(?i)` + strings.Join(words, `\s+(?:[#*/;-]+\s*)?`))
+}
+
+// ProcessLicenseType handles policies of type "license"
+func ProcessLicenseType(policy Policy, targetDir string, filePaths []string) error {
+	if policy.Type != "license" {
+		return nil
+	}
+
+	findings := executeLicense(policy, filePaths)
+	sarifReport := GenerateLicenseSARIFReport(policy, findings)
+
+	reportID := policy.ID
+	if policy.RunID != "" {
+		reportID = policy.RunID
+	}
+	if err := writeSARIFReport(reportID, sarifReport); err != nil {
+		log.Error().Err(err).Msg("error writing SARIF report")
+		return fmt.Errorf("error writing SARIF report: %w", err)
+	}
+
+	log.Debug().Msgf("Policy %s processed. %d licenses detected", policy.ID, len(findings))
+	return nil
+}
+
+func executeLicense(policy Policy, filePaths []string) []licenseFinding {
+	headerLines := policy.License.HeaderLines
+	if headerLines <= 0 {
+		headerLines = licenseDefaultHeaderLines
+	}
+
+	var findings []licenseFinding
+	for _, file := range filePaths {
+		if isBinaryFile(file) {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Debug().Err(err).Str("file", file).Msg("license skipped unreadable file")
+			continue
+		}
+		for _, finding := range detectLicenses(file, content, headerLines) {
+			finding.status = classifyLicense(policy.License, finding.license)
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// detectLicenses returns the licenses declared by a file : SPDX identifiers and license texts in its header,
+// the text of LICENSE files and the license fields of manifests
+func detectLicenses(file string, content []byte, headerLines int) []licenseFinding {
+	var findings []licenseFinding
+	name := filepath.Base(file)
+
+	switch strings.ToLower(name) {
+	case "package.json", "package-lock.json", "npm-shrinkwrap.json", "pom.xml", "cargo.toml", "pyproject.toml":
+		return manifestLicenses(file, content)
+	}
+
+	header := content
+	isLicenseFile := licenseFileName.MatchString(name)
+	if !isLicenseFile {
+		header = leadingLines(content, headerLines)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(header))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if match := spdxIdentifierLine.FindStringSubmatch(scanner.Text()); match != nil {
+			if expression := cleanSPDXExpression(match[1]); expression != "" {
+				findings = append(findings, licenseFinding{file: file, line: lineNumber, license: expression, source: licenseSourceSPDX})
+			}
+		}
+	}
+	if len(findings) > 0 {
+		return findings
+	}
+
+	if license, line := matchLicenseText(header); license != "" {
+		source := licenseSourceHeader
+		if isLicenseFile {
+			source = licenseSourceFile
+		}
+		return []licenseFinding{{file: file, line: line, license: license, source: source}}
+	}
+	if isLicenseFile && len(bytes.TrimSpace(content)) > 0 {
+		return []licenseFinding{{file: file, line: 1, license: licenseNoAssertion, source: licenseSourceFile}}
+	}
+	return nil
+}
+
+func leadingLines(content []byte, n int) []byte {
+	end := 0
+	for i := 0; i < n && end < len(content); i++ {
+		next := bytes.IndexByte(content[end:], '\n')
+		if next < 0 {
+			return content
+		}
+		end += next + 1
+	}
+	return content[:end]
+}
+
+// cleanSPDXExpression drops the comment terminators following an SPDX identifier
+func cleanSPDXExpression(expression string) string {
+	for _, terminator := range []string{"*/", "-->", "#}", "*)"} {
+		if index := strings.Index(expression, terminator); index >= 0 {
+			expression = expression[:index]
+		}
+	}
+	return strings.Trim(strings.TrimSpace(expression), `"',;`)
+}
+
+// matchLicenseText returns the license of a text with the line where it is named. Named licenses are tried
+// in the order their name appears and the first version whose phrases all appear wins, the wording of
+// unnamed licenses (MIT, BSD, ISC) is only checked when no named license matches.
+func matchLicenseText(text []byte) (string, int) {
+	type namedLicense struct {
+		index, offset int
+	}
+	var named []namedLicense
+	for i, fingerprint := range licenseFingerprintRe {
+		if fingerprint.name == nil {
+			continue
+		}
+		if location := fingerprint.name.FindIndex(text); location != nil {
+			named = append(named, namedLicense{index: i, offset: location[0]})
+		}
+	}
+	// stable, versions of the same name keep their order
+	sort.SliceStable(named, func(a, b int) bool { return named[a].offset < named[b].offset })
+
+	for _, candidate := range named {
+		if containsLicensePhrases(text, licenseFingerprintRe[candidate.index].phrases) {
+			return licenseFingerprints[candidate.index].id, 1 + bytes.Count(text[:candidate.offset], []byte("\n"))
+		}
+	}
+
+	for i, fingerprint := range licenseFingerprintRe {
+		if fingerprint.name != nil || !containsLicensePhrases(text, fingerprint.phrases) {
+			continue
+		}
+		first := fingerprint.phrases[0].FindIndex(text)
+		return licenseFingerprints[i].id, 1 + bytes.Count(text[:first[0]], []byte("\n"))
+	}
+	return "", 0
+}
+
+func containsLicensePhrases(text []byte, phrases []*regexp.Regexp) bool {
+	for _, phrase := range phrases {
+		if !phrase.Match(text) {
+			return false
+		}
+	}
+	return true
+}
+
+// manifestLicenses reads the license fields of package manifests, lockfiles also list the licenses of their packages
+func manifestLicenses(file string, content []byte) []licenseFinding {
+	var findings []licenseFinding
+	add := func(license string, line int) {
+		if license = normalizeLicenseName(license); license != "" {
+			findings = append(findings, licenseFinding{file: file, line: line, license: license, source: licenseSourceManifest})
+		}
+	}
+
+	switch strings.ToLower(filepath.Base(file)) {
+	case "package.json":
+		root, err := parseJSONNode(content)
+		if err != nil {
+			log.Debug().Err(err).Str("file", file).Msg("license skipped invalid package.json")
+			return nil
+		}
+		if value := yamlMappingValue(root, "license"); value != nil {
+			if value.Kind == yaml.MappingNode {
+				value = yamlMappingValue(value, "type")
+			}
+			if value != nil {
+				add(value.Value, value.Line)
+			}
+		}
+	case "package-lock.json", "npm-shrinkwrap.json":
+		packages, err := parsePackageLock(file, content)
+		if err != nil {
+			log.Debug().Err(err).Str("file", file).Msg("license skipped invalid lockfile")
+			return nil
+		}
+		for _, pkg := range packages {
+			add(pkg.License, pkg.Line)
+		}
+	case "pom.xml":
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		inLicenses := false
+		for lineNumber := 1; scanner.Scan(); lineNumber++ {
+			line := scanner.Text()
+			switch {
+			case strings.Contains(line, "<licenses>"):
+				inLicenses = true
+			case strings.Contains(line, "</licenses>"):
+				inLicenses = false
+			case inLicenses && strings.Contains(line, "<name>"):
+				name := line[strings.Index(line, "<name>")+len("<name>"):]
+				if end := strings.Index(name, "</name>"); end >= 0 {
+					add(name[:end], lineNumber)
+				}
+			}
+		}
+	default:
+		// Cargo.toml and pyproject.toml
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for lineNumber := 1; scanner.Scan(); lineNumber++ {
+			if match := tomlLicenseLine.FindStringSubmatch(scanner.Text()); match != nil {
+				add(match[1], lineNumber)
+			}
+		}
+	}
+	return findings
+}
+
+// normalizeLicenseName maps license names to SPDX identifiers when known
+func normalizeLicenseName(name string) string {
+	name = strings.TrimSpace(name)
+	if alias, ok := licenseAliases[strings.ToLower(name)]; ok {
+		return alias
+	}
+	return name
+}
+
+// licenseStatusRanks orders the license statuses from the best to the worst
+var licenseStatusRanks = []string{licenseAllowed, licenseReview, licenseDenied}
+
+// classifyLicense returns the status of an SPDX expression : with OR the best alternative counts, with AND the worst part.
+// A license WITH an exception is classified as "<id> WITH <exception>" when a list names it so, otherwise as its id.
+func classifyLicense(config LicenseConfig, expression string) string {
+	return licenseStatusRanks[parseLicenseExpression(expression).evaluate(func(license, exception string) int {
+		if exception != "" {
+			if term := license + " WITH " + exception; matchLicenseList(config.Deny, term) || matchLicenseList(config.Review, term) || matchLicenseList(config.Allow, term) {
+				license = term
+			}
+		}
+		return slices.Index(licenseStatusRanks, classifyLicenseID(config, license))
+	})]
+}
+
+func classifyLicenseID(config LicenseConfig, id string) string {
+	// "-or-later" and "+" variants are listed under their base license
+	id = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(id, "+"), "-or-later"), "-only")
+	switch {
+	case matchLicenseList(config.Deny, id):
+		return licenseDenied
+	case matchLicenseList(config.Review, id):
+		return licenseReview
+	case matchLicenseList(config.Allow, id):
+		return licenseAllowed
+	}
+	switch strings.ToLower(config.Unknown) {
+	case "allow":
+		return licenseAllowed
+	case "deny":
+		return licenseDenied
+	}
+	return licenseReview
+}
+
+// matchLicenseList matches SPDX identifiers case-insensitively, entries may use * wildcards (GPL-*)
+func matchLicenseList(list []string, id string) bool {
+	for _, entry := range list {
+		if matched, _ := path.Match(strings.ToLower(entry), strings.ToLower(id)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func licenseStatusLevel(policy Policy, status string) SARIFLevel {
+	switch status {
+	case licenseDenied:
+		return calculateSARIFLevel(policy, environment)
+	case licenseReview:
+		return SARIFWarning
+	}
+	return SARIFNote
+}
+
+func GenerateLicenseSARIFReport(policy Policy, findings []licenseFinding) SARIFReport {
+	sarifReport := newPolicySARIFReport(policy)
+
+	properties := policyResultProperties(policy)
+
+	if len(findings) == 0 {
+		sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, compliantResult(policy, "No licenses found"))
+	}
+
+	inventory := make(map[string]int)
+	sources := sourceLines{}
+	for _, finding := range findings {
+		inventory[finding.license]++
+
+		level := licenseStatusLevel(policy, finding.status)
+		message := fmt.Sprintf("License %s is allowed (%s)", finding.license, finding.source)
+		if finding.status != licenseAllowed {
+			message = fmt.Sprintf("Policy violation: %s license %s requires %s (%s)", policy.Metadata.Name, finding.license, finding.status, finding.source)
+			if finding.status == licenseDenied {
+				message = fmt.Sprintf("Policy violation: %s license %s is denied (%s)", policy.Metadata.Name, finding.license, finding.source)
+			}
+		}
+
+		region := Region{StartLine: finding.line, StartColumn: 1, EndLine: finding.line, EndColumn: 1}
+		if lines := sources.get(finding.file); finding.line >= 1 && finding.line <= len(lines) {
+			line := strings.TrimRight(lines[finding.line-1], "\r\n")
+			region.EndColumn = len(line) + 1
+			region.Snippet = Snippet{Text: strings.TrimSpace(line)}
+		}
+
+		result := Result{
+			RuleID:  policy.ID,
+			Level:   level,
+			Message: Message{Text: message},
+			Locations: []Location{{PhysicalLocation: PhysicalLocation{
+				ArtifactLocation: ArtifactLocation{URI: filepath.ToSlash(finding.file)},
+				Region:           region,
+				ContextRegion:    sources.contextRegion(finding.file, region, policy.ContextLines),
+			}}},
+			Properties: properties,
+		}
+		result.Properties.SarifInt = sarifLevelToInt(level)
+		result.Properties.License = finding.license
+		result.Properties.LicenseSource = finding.source
+		result.Properties.LicenseStatus = finding.status
+
+		sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, result)
+	}
+
+	sarifReport.Runs[0].Results = applyExceptions(virtualizeResultURIs(sarifReport.Runs[0].Results))
+	if len(inventory) > 0 {
+		sarifReport.Runs[0].Invocations[0].Properties.LicenseInventory = inventory
+	}
+	sarifReport.Runs[0].Invocations[0].Properties.ReportCompliant = ComplianceStatus(sarifReport)
+
+	if outputTypeMatrixConfig.LOG {
+		PostResultsToComplianceLog(sarifReport)
+	}
+
+	return sarifReport
+}
@@ -0,0 +1,983 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Lint issue severities
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintIssue is a single problem found in a policy file
+type LintIssue struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	PolicyID string `json:"policy_id,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	position := fmt.Sprintf("%s:%d", i.File, i.Line)
+	if i.Column > 0 {
+		position = fmt.Sprintf("%s:%d", position, i.Column)
+	}
+	subject := i.Field
+	if i.PolicyID != "" {
+		subject = strings.TrimSpace(fmt.Sprintf("[%s] %s", i.PolicyID, i.Field))
+	}
+	if subject == "" {
+		return fmt.Sprintf("%s: %s: %s", position, i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s %s: %s", position, i.Severity, subject, i.Message)
+}
+
+// policyTypeRequirement lists the sections each policy type needs to run
+type policyTypeRequirement struct {
+	regex         bool
+	schema        bool
+	rego          bool
+	api           bool
+	runtime       bool
+	secrets       bool
+	dependencies  bool
+	license       bool
+	regexOrSchema bool
+	schemaOrRego  bool
+}
+
+var policyTypeRequirements = map[string]policyTypeRequirement{
+	"scan":         {regex: true},
+	"assure":       {regex: true},
+	"runtime":      {runtime: true},
+	"api":          {api: true, regexOrSchema: true},
+	"yml":          {schema: true},
+	"toml":         {schema: true},
+	"json":         {schema: true},
+	"ini":          {schema: true},
+	"rego":         {rego: true},
+	"secrets":      {secrets: true},
+	"dependencies": {dependencies: true},
+	"license":      {license: true},
+	"dockerfile":   {schemaOrRego: true},
+	"hcl":          {schemaOrRego: true},
+}
+
+var (
+	lintWebhookEventTypes = []string{"minimal", "results", "policy", "report", "log", "bulk"}
+	lintAuthTypes         = []string{"basic", "bearer", "header", "authorization"}
+	lintHTTPMethods       = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	lintConfidences       = []string{"high", "medium", "low", "info"}
+	lintOutputTypes       = []string{"sarif", "log"}
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Policy file tooling",
+}
+
+var policyLintEngine string
+
+var policyLintCmd = &cobra.Command{
+	Use:   "lint [FILE...]",
+	Short: "Validate policy files and report all errors with their YAML line numbers",
+	Long: `Validate policy files up front : policy types, required fields per type, regex compilation (PCRE2 via ripgrep or RE2 for the native engine),
+CUE schemas, Rego modules and queries, cron expressions, duplicate IDs and webhook hooks.
+
+Examples:
+  $ intercept policy lint policies/scan.yaml policies/config.yaml
+  $ intercept policy lint -p policies/scan.yaml`,
+	Run: runPolicyLint,
+}
+
+func init() {
+	rootCmd.AddCommand(policyCmd)
+	policyCmd.AddCommand(policyLintCmd)
+	policyLintCmd.Flags().StringArrayVarP(&policyFiles, "policy", "p", nil, "Policy <FILEPATH> or <DIRECTORY> to lint")
+	policyLintCmd.Flags().StringVar(&policyLintEngine, "engine", "", "Regex engine the patterns are validated for : native,ripgrep (default ripgrep)")
+}
+
+func runPolicyLint(cmd *cobra.Command, args []string) {
+	files, err := expandPolicyPaths(append(args, policyFiles...))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error listing policy files")
+	}
+	if len(files) == 0 {
+		log.Fatal().Msg("At least one policy file is required")
+	}
+
+	errorCount, warningCount := 0, 0
+	for _, file := range files {
+		issues, err := LintPolicyFile(file)
+		if err != nil {
+			log.Fatal().Err(err).Str("file", file).Msg("Error reading policy file")
+		}
+		for _, issue := range issues {
+			fmt.Println(issue.String())
+			if issue.Severity == LintError {
+				errorCount++
+			} else {
+				warningCount++
+			}
+		}
+	}
+
+	fmt.Printf("%d file(s) linted: %d error(s), %d warning(s)\n", len(files), errorCount, warningCount)
+
+	if errorCount > 0 {
+		os.Exit(1)
+	}
+}
+
+// LintPolicyFile validates a policy file and returns every issue found, sorted as they appear in the file
+func LintPolicyFile(filename string) ([]LintIssue, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return LintPolicyData(filename, data), nil
+}
+
+// LintPolicyData validates the content of a policy file, filename labels issues and resolves includes
+func LintPolicyData(filename string, data []byte) []LintIssue {
+	l := &policyLinter{file: filename}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		l.addYAMLError(err)
+		return l.issues
+	}
+	if len(document.Content) == 0 {
+		l.add(&document, LintError, "", "", "policy file is empty")
+		return l.issues
+	}
+	root := document.Content[0]
+
+	var pf PolicyFile
+	if err := root.Decode(&pf); err != nil {
+		l.addYAMLError(err)
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		switch key := root.Content[i]; key.Value {
+		case "Include", "Config", "Version", "Namespace", "Policies", "Overrides":
+		default:
+			l.add(key, LintWarning, "", key.Value, "unknown field %q is ignored", key.Value)
+		}
+	}
+	l.lintConfig(pf.Config, yamlMappingValue(root, "Config"))
+	// patterns are validated for the engine an audit of the file would use, unless --engine is set
+	scanEngine = policyLintEngine
+	if err := resolveScanEngine(pf.Config); err != nil {
+		scanEngine = engineRipgrep
+	}
+	l.lintComposition(pf, root)
+
+	policiesNode := yamlMappingValue(root, "Policies")
+	if policiesNode == nil || len(pf.Policies) == 0 {
+		// overlays may only include and override other files
+		if len(pf.Include) == 0 && len(pf.Overrides) == 0 {
+			l.add(root, LintWarning, "", "Policies", "no policies defined")
+		}
+		sort.SliceStable(l.issues, func(a, b int) bool { return l.issues[a].Line < l.issues[b].Line })
+		return l.issues
+	}
+
+	seen := make(map[string]*yaml.Node)
+	for i, policy := range pf.Policies {
+		node := policiesNode
+		if i < len(policiesNode.Content) {
+			node = policiesNode.Content[i]
+		}
+
+		if policy.ID != "" {
+			normalized := NormalizePolicyName(policy.ID)
+			if first, ok := seen[normalized]; ok {
+				l.add(yamlMappingValue(node, "id"), LintError, policy.ID, "id", "duplicate policy ID %s, first defined on line %d", normalized, first.Line)
+			} else {
+				seen[normalized] = node
+			}
+		}
+
+		l.lintPolicy(policy, node)
+	}
+
+	sort.SliceStable(l.issues, func(a, b int) bool { return l.issues[a].Line < l.issues[b].Line })
+
+	return l.issues
+}
+
+type policyLinter struct {
+	file   string
+	issues []LintIssue
+}
+
+func (l *policyLinter) add(node *yaml.Node, severity, policyID, field, format string, args ...interface{}) {
+	issue := LintIssue{
+		File:     l.file,
+		Severity: severity,
+		PolicyID: policyID,
+		Field:    field,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	l.issues = append(l.issues, issue)
+}
+
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// addYAMLError turns yaml parse and type errors into issues, keeping their line numbers
+func (l *policyLinter) addYAMLError(err error) {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+	for _, message := range messages {
+		issue := LintIssue{File: l.file, Severity: LintError, Message: message}
+		if m := yamlErrorLine.FindStringSubmatch(message); m != nil {
+			issue.Line, _ = strconv.Atoi(m[1])
+			issue.Message = m[2]
+		}
+		l.issues = append(l.issues, issue)
+	}
+}
+
+func (l *policyLinter) lintConfig(config Config, node *yaml.Node) {
+	if node == nil {
+		return
+	}
+	l.checkUnknownKeys(node, reflect.TypeOf(config), "", "Config")
+
+	flags := yamlMappingValue(node, "Flags")
+	if flags != nil {
+		for _, key := range []string{"policy_schedule", "report_schedule"} {
+			if value := yamlMappingValue(flags, key); value != nil && value.Value != "" && !validateCronExpression(value.Value) {
+				l.add(value, LintError, "", "Config.Flags."+key, "invalid cron expression %q", value.Value)
+			}
+		}
+		if value := yamlMappingValue(flags, "fail_on"); value != nil {
+			if _, err := parseFailOn(value.Value); err != nil {
+				l.add(value, LintError, "", "Config.Flags.fail_on", "%v", err)
+			}
+		}
+		if value := yamlMappingValue(flags, "engine"); value != nil && !containsFold([]string{engineNative, engineRipgrep}, value.Value) {
+			l.add(value, LintError, "", "Config.Flags.engine", "unknown engine %q (expected native or ripgrep)", value.Value)
+		}
+		if value := yamlMappingValue(flags, "redact"); value != nil && !containsFold(redactModes, value.Value) {
+			l.add(value, LintError, "", "Config.Flags.redact", "unknown redaction mode %q (expected one of %s)", value.Value, strings.Join(redactModes, ","))
+		}
+		if value := yamlMappingValue(flags, "ignore"); value != nil {
+			l.lintIgnorePatterns(value, "", "Config.Flags.ignore")
+		}
+		if value := yamlMappingValue(flags, "output_type"); value != nil {
+			for _, item := range value.Content {
+				if !containsFold(lintOutputTypes, item.Value) {
+					l.add(item, LintError, "", "Config.Flags.output_type", "unknown output type %q (expected SARIF or LOG)", item.Value)
+				}
+			}
+		}
+	}
+
+	hooksNode := yamlMappingValue(node, "Hooks")
+	names := make(map[string]int)
+	for i, hook := range config.Hooks {
+		hookNode := hooksNode
+		if hooksNode != nil && i < len(hooksNode.Content) {
+			hookNode = hooksNode.Content[i]
+		}
+		field := fmt.Sprintf("Config.Hooks[%d]", i)
+		l.checkUnknownKeys(hookNode, reflect.TypeOf(hook), "", field)
+
+		if hook.Name == "" {
+			l.add(hookNode, LintError, "", field+".name", "hook name is required")
+		} else if line, ok := names[hook.Name]; ok {
+			l.add(yamlMappingValue(hookNode, "name"), LintError, "", field+".name", "duplicate hook name %q, first defined on line %d", hook.Name, line)
+		} else {
+			names[hook.Name] = lineOf(yamlMappingValue(hookNode, "name"))
+		}
+
+		l.checkURL(hook.Endpoint, hookNode, "endpoint", "", field+".endpoint")
+		if hook.Method != "" && !containsFold(lintHTTPMethods, hook.Method) {
+			l.add(yamlMappingValue(hookNode, "method"), LintError, "", field+".method", "unsupported HTTP method %q", hook.Method)
+		}
+		if hook.RetryDelay != "" {
+			if _, err := time.ParseDuration(hook.RetryDelay); err != nil {
+				l.add(yamlMappingValue(hookNode, "retry_delay"), LintError, "", field+".retry_delay", "invalid duration %q", hook.RetryDelay)
+			}
+		}
+		if hook.RetryAttempts < 0 || hook.TimeoutSeconds < 0 {
+			l.add(hookNode, LintError, "", field, "retry_attempts and timeout_seconds must not be negative")
+		}
+		if len(hook.EventTypes) == 0 {
+			l.add(hookNode, LintWarning, "", field+".event_types", "no event types, the hook will never be called")
+		}
+		if eventsNode := yamlMappingValue(hookNode, "event_types"); eventsNode != nil {
+			for _, item := range eventsNode.Content {
+				if !containsFold(lintWebhookEventTypes, item.Value) {
+					l.add(item, LintError, "", field+".event_types", "unknown event type %q (expected one of %s)", item.Value, strings.Join(lintWebhookEventTypes, ","))
+				}
+			}
+		}
+		l.checkAuth(hook.Auth, yamlMappingValue(hookNode, "auth"), "", field+".auth")
+	}
+}
+
+func (l *policyLinter) lintPolicy(policy Policy, node *yaml.Node) {
+	id := policy.ID
+	l.checkUnknownKeys(node, reflect.TypeOf(policy), id, "")
+
+	if id == "" {
+		l.add(node, LintError, "", "id", "policy id is required")
+	}
+
+	requirement, known := policyTypeRequirements[policy.Type]
+	switch {
+	case policy.Type == "":
+		l.add(node, LintError, id, "type", "policy type is required")
+	case !known:
+		l.add(yamlMappingValue(node, "type"), LintError, id, "type", "unknown policy type %q (expected one of %s)", policy.Type, strings.Join(sortedPolicyTypes(), ","))
+	}
+
+	if policy.Metadata.Name == "" {
+		l.add(node, LintWarning, id, "metadata.name", "policy has no name")
+	}
+
+	l.lintEnforcement(policy, node)
+
+	if policy.FilePattern != "" {
+		if _, err := regexp.Compile(policy.FilePattern); err != nil {
+			l.add(yamlMappingValue(node, "filepattern"), LintError, id, "filepattern", "invalid file pattern: %v", err)
+		}
+	}
+
+	if value := yamlMappingValue(node, "ignore"); value != nil {
+		l.lintIgnorePatterns(value, id, "ignore")
+	}
+	for _, field := range []string{"include", "exclude"} {
+		if value := yamlMappingValue(node, field); value != nil {
+			for _, item := range value.Content {
+				if _, err := compileGlob(item.Value); err != nil {
+					l.add(item, LintError, id, field, "%v", err)
+				}
+			}
+		}
+	}
+	if _, err := parseFileSize(policy.MaxFileSize); err != nil {
+		l.add(yamlMappingValue(node, "max_file_size"), LintError, id, "max_file_size", "%v (expected bytes or KB, MB, GB)", err)
+	}
+	if policy.MaxDepth < 0 {
+		l.add(yamlMappingValue(node, "max_depth"), LintError, id, "max_depth", "max_depth must not be negative")
+	}
+
+	if policy.Redact != "" && !containsFold(redactModes, policy.Redact) {
+		l.add(yamlMappingValue(node, "redact"), LintError, id, "redact", "unknown redaction mode %q (expected one of %s)", policy.Redact, strings.Join(redactModes, ","))
+	}
+
+	if policy.Format != "" {
+		format, known := fileFormats[strings.ToLower(policy.Format)]
+		switch {
+		case !known:
+			l.add(yamlMappingValue(node, "format"), LintError, id, "format", "unknown format %q (expected one of %s)", policy.Format, strings.Join(sortedFileFormats(), ","))
+		case !containsString(formatPolicyTypes, policy.Type):
+			l.add(yamlMappingValue(node, "format"), LintWarning, id, "format", "format is only used by %s policies", strings.Join(formatPolicyTypes, ", "))
+		case policy.Schema.Patch && !format.patch:
+			l.add(yamlMappingValue(node, "format"), LintError, id, "format", "_schema.patch can't write %s files", strings.ToLower(policy.Format))
+		}
+	}
+
+	if policy.ContextLines < 0 {
+		l.add(yamlMappingValue(node, "context_lines"), LintError, id, "context_lines", "context_lines must not be negative")
+	} else if policy.ContextLines > maxContextLines {
+		l.add(yamlMappingValue(node, "context_lines"), LintWarning, id, "context_lines", "context_lines is capped at %d", maxContextLines)
+	}
+
+	regexNode := yamlMappingValue(node, "_regex")
+	schemaNode := yamlMappingValue(node, "_schema")
+
+	if requirement.regex && len(policy.Regex) == 0 {
+		l.add(node, LintError, id, "_regex", "%s policies require at least one _regex pattern", policy.Type)
+	}
+	if requirement.schema && policy.Schema.Structure == "" {
+		l.add(node, LintError, id, "_schema.structure", "%s policies require a CUE _schema.structure", policy.Type)
+	}
+	if requirement.regexOrSchema && len(policy.Regex) == 0 && policy.Schema.Structure == "" {
+		l.add(node, LintError, id, "_regex", "%s policies require either _regex patterns or a _schema.structure", policy.Type)
+	}
+	if requirement.schemaOrRego && policy.Schema.Structure == "" && yamlMappingValue(node, "_rego") == nil {
+		l.add(node, LintError, id, "_schema", "%s policies require either a _schema.structure or a _rego section", policy.Type)
+	}
+	if requirement.schemaOrRego && policy.Schema.Structure != "" && yamlMappingValue(node, "_rego") != nil {
+		l.add(yamlMappingValue(node, "_rego"), LintWarning, id, "_rego", "_rego takes precedence, _schema.structure is ignored")
+	}
+	if documentsNode := yamlMappingValue(schemaNode, "documents"); documentsNode != nil {
+		l.lintDocumentSelector(policy, documentsNode)
+	}
+	if policy.Schema.Patch && !requirement.schema {
+		l.add(yamlMappingValue(schemaNode, "patch"), LintWarning, id, "_schema.patch", "patch is ignored for %s policies", policy.Type)
+	}
+
+	if regexNode != nil {
+		patternIDs := make(map[string]int)
+		for i, item := range regexNode.Content {
+			field := fmt.Sprintf("_regex[%d]", i)
+			patternNode := item
+			if item.Kind == yaml.MappingNode {
+				var pattern RegexPattern
+				if err := item.Decode(&pattern); err != nil {
+					l.add(item, LintError, id, field, "%v", err)
+					continue
+				}
+				l.checkUnknownKeys(item, reflect.TypeOf(pattern), id, field)
+				if patternNode = yamlMappingValue(item, "pattern"); patternNode == nil || pattern.Pattern == "" {
+					l.add(item, LintError, id, field+".pattern", "pattern entry without pattern")
+					continue
+				}
+				if pattern.ID != "" {
+					if first, ok := patternIDs[pattern.ID]; ok {
+						l.add(yamlMappingValue(item, "id"), LintError, id, field+".id", "duplicate pattern ID %s, first defined on line %d", pattern.ID, first)
+					} else {
+						patternIDs[pattern.ID] = lineOf(item)
+					}
+				}
+				if _, ok := parsePatternSeverity(pattern.Severity); pattern.Severity != "" && !ok {
+					l.add(yamlMappingValue(item, "severity"), LintError, id, field+".severity", "unknown severity %q (expected one of %s)", pattern.Severity, strings.Join(patternSeverities, ","))
+				}
+				if (pattern.Entropy != 0 || pattern.Checksum != "") && !requirement.secrets {
+					l.add(item, LintWarning, id, field, "entropy and checksum are only used by secrets policies")
+				}
+				if _, ok := secretChecksums[strings.ToLower(pattern.Checksum)]; pattern.Checksum != "" && !ok {
+					l.add(yamlMappingValue(item, "checksum"), LintError, id, field+".checksum", "unknown checksum %q (expected %s or %s)", pattern.Checksum, checksumGitHub, checksumLuhn)
+				}
+			}
+			if requirement.secrets {
+				// secrets policies always run on the native engine
+				if _, err := regexp.Compile(patternNode.Value); err != nil {
+					l.add(patternNode, LintError, id, field, "not a valid RE2 expression: %v", err)
+				}
+				continue
+			}
+			if useNativeEngine() {
+				if _, err := regexp.Compile("(?im)" + patternNode.Value); err != nil {
+					l.add(patternNode, LintError, id, field, "not a valid RE2 expression for the native engine: %v", err)
+				}
+				continue
+			}
+			if verified, err := compilePCRE2(patternNode.Value); err != nil {
+				severity := LintError
+				if !verified {
+					severity = LintWarning
+				}
+				l.add(patternNode, severity, id, field, "%v", err)
+			}
+		}
+	}
+
+	if policy.Schema.Structure != "" {
+		l.checkCUE(policy.Schema.Structure, yamlMappingValue(schemaNode, "structure"), id)
+	}
+
+	if requirement.rego {
+		l.lintRego(policy, yamlMappingValue(node, "_rego"), node, true)
+	} else if requirement.schemaOrRego && yamlMappingValue(node, "_rego") != nil {
+		// policy_data is optional when the input is built by INTERCEPT
+		l.lintRego(policy, yamlMappingValue(node, "_rego"), node, false)
+	}
+	if requirement.secrets {
+		l.lintSecrets(policy, yamlMappingValue(node, "_secrets"))
+	} else if yamlMappingValue(node, "_secrets") != nil {
+		l.add(yamlMappingValue(node, "_secrets"), LintWarning, id, "_secrets", "_secrets is only used by secrets policies")
+	}
+	if requirement.dependencies {
+		l.lintDependencies(policy, yamlMappingValue(node, "_dependencies"), node)
+	} else if yamlMappingValue(node, "_dependencies") != nil {
+		l.add(yamlMappingValue(node, "_dependencies"), LintWarning, id, "_dependencies", "_dependencies is only used by dependencies policies")
+	}
+	if requirement.license {
+		l.lintLicense(policy, yamlMappingValue(node, "_license"), node)
+	} else if yamlMappingValue(node, "_license") != nil {
+		l.add(yamlMappingValue(node, "_license"), LintWarning, id, "_license", "_license is only used by license policies")
+	}
+	if requirement.api {
+		apiNode := yamlMappingValue(node, "_api")
+		l.checkURL(policy.API.Endpoint, apiNode, "endpoint", id, "_api.endpoint")
+		if policy.API.Method != "" && !containsFold(lintHTTPMethods, policy.API.Method) {
+			l.add(yamlMappingValue(apiNode, "method"), LintError, id, "_api.method", "unsupported HTTP method %q", policy.API.Method)
+		}
+		l.checkAuth(policy.API.Auth, yamlMappingValue(apiNode, "auth"), id, "_api.auth")
+	}
+	if requirement.runtime {
+		runtimeNode := yamlMappingValue(node, "_runtime")
+		if policy.Runtime.Config == "" {
+			l.add(node, LintError, id, "_runtime.config", "runtime policies require a _runtime.config goss file")
+		} else if _, err := os.Stat(policy.Runtime.Config); err != nil {
+			l.add(yamlMappingValue(runtimeNode, "config"), LintWarning, id, "_runtime.config", "goss file not found relative to the working directory: %s", policy.Runtime.Config)
+		}
+	}
+
+	// schedulers and watchers
+	if policy.Schedule != "" && !validateCronExpression(policy.Schedule) {
+		l.add(yamlMappingValue(node, "schedule"), LintError, id, "schedule", "invalid cron expression %q", policy.Schedule)
+	}
+	if policy.Schedule != "" && (policy.Observe != "" || policy.Runtime.Observe != "") {
+		l.add(yamlMappingValue(node, "schedule"), LintError, id, "schedule", "schedule and observe are mutually exclusive, observe would be skipped")
+	}
+	if policy.Type == "runtime" && policy.Observe != "" {
+		l.add(yamlMappingValue(node, "observe"), LintWarning, id, "observe", "runtime policies are observed through _runtime.observe, observe is ignored")
+	}
+	if policy.Type != "runtime" && policy.Runtime.Observe != "" {
+		l.add(yamlMappingValue(yamlMappingValue(node, "_runtime"), "observe"), LintWarning, id, "_runtime.observe", "_runtime.observe is only used by runtime policies")
+	}
+
+	testsNode := yamlMappingValue(node, "tests")
+	for i, test := range policy.Tests {
+		testNode := testsNode
+		if testsNode != nil && i < len(testsNode.Content) {
+			testNode = testsNode.Content[i]
+		}
+		field := fmt.Sprintf("tests[%d]", i)
+		if (test.Fixture == "") == (test.Content == "") {
+			l.add(testNode, LintError, id, field, "exactly one of fixture or content is required")
+		} else if test.Fixture != "" {
+			fixture := test.Fixture
+			if !filepath.IsAbs(fixture) {
+				fixture = filepath.Join(filepath.Dir(l.file), fixture)
+			}
+			if _, err := os.Stat(fixture); err != nil {
+				l.add(yamlMappingValue(testNode, "fixture"), LintError, id, field+".fixture", "fixture not found: %s", fixture)
+			}
+		}
+		if test.Expect.Compliant == nil && test.Expect.Matches == nil && len(test.Expect.Messages) == 0 && len(test.Expect.Absent) == 0 {
+			l.add(testNode, LintWarning, id, field+".expect", "test has no expectation")
+		}
+	}
+
+	exceptionsNode := yamlMappingValue(node, "exceptions")
+	for i, exception := range policy.Exceptions {
+		exceptionNode := exceptionsNode
+		if exceptionsNode != nil && i < len(exceptionsNode.Content) {
+			exceptionNode = exceptionsNode.Content[i]
+		}
+		exception.PolicyID = NormalizePolicyName(id)
+		if err := prepareException(&exception); err != nil {
+			l.add(exceptionNode, LintError, id, fmt.Sprintf("exceptions[%d]", i), "%v", err)
+		}
+	}
+}
+
+func (l *policyLinter) lintComposition(pf PolicyFile, root *yaml.Node) {
+	overridesNode := yamlMappingValue(root, "Overrides")
+	for i, override := range pf.Overrides {
+		node := overridesNode
+		if overridesNode != nil && i < len(overridesNode.Content) {
+			node = overridesNode.Content[i]
+			l.checkUnknownKeys(node, reflect.TypeOf(override), override.ID, fmt.Sprintf("Overrides[%d]", i))
+		}
+		field := fmt.Sprintf("Overrides[%d]", i)
+		if override.ID == "" {
+			l.add(node, LintError, "", field+".id", "override without id")
+			continue
+		}
+		if !override.Disabled && len(override.Enforcement) == 0 {
+			l.add(node, LintWarning, override.ID, field, "override changes nothing, set disabled or enforcement")
+		}
+		if len(override.Enforcement) > 0 {
+			l.lintEnforcement(Policy{ID: override.ID, Enforcement: override.Enforcement}, node)
+		}
+	}
+
+	if len(pf.Include) == 0 && len(pf.Overrides) == 0 {
+		return
+	}
+	// resolve includes and overrides the same way audit does
+	if _, err := os.Stat(l.file); err != nil {
+		return
+	}
+	if _, err := LoadPolicySources([]string{l.file}, ""); err != nil {
+		node := yamlMappingValue(root, "Include")
+		if node == nil {
+			node = overridesNode
+		}
+		l.add(node, LintError, "", "Include", "%v", err)
+	}
+}
+
+func (l *policyLinter) lintSecrets(policy Policy, secretsNode *yaml.Node) {
+	if policy.Secrets.Entropy < 0 || policy.Secrets.Entropy > 8 {
+		l.add(yamlMappingValue(secretsNode, "entropy"), LintError, policy.ID, "_secrets.entropy", "entropy must be between 0 and 8 bits per character")
+	}
+	for i, name := range policy.Secrets.Detectors {
+		if _, ok := secretDetectors[strings.ToLower(name)]; !ok {
+			l.add(yamlMappingValue(secretsNode, "detectors"), LintError, policy.ID, fmt.Sprintf("_secrets.detectors[%d]", i), "unknown detector %q (expected one of %s)", name, strings.Join(secretDetectorNames(), ","))
+		}
+	}
+	allowlistNode := yamlMappingValue(secretsNode, "allowlist")
+	for i, pattern := range policy.Secrets.Allowlist.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			l.add(yamlMappingValue(allowlistNode, "patterns"), LintError, policy.ID, fmt.Sprintf("_secrets.allowlist.patterns[%d]", i), "not a valid RE2 expression: %v", err)
+		}
+	}
+}
+
+func (l *policyLinter) lintDependencies(policy Policy, dependenciesNode, policyNode *yaml.Node) {
+	config := policy.Dependencies
+	if len(config.Deny) == 0 && len(config.Constraints) == 0 && len(config.Licenses.Allow) == 0 && len(config.Licenses.Deny) == 0 && config.Advisories == "" {
+		l.add(policyNode, LintError, policy.ID, "_dependencies", "dependencies policies require deny, constraints, licenses or advisories")
+	}
+	for i, ecosystem := range config.Ecosystems {
+		if !containsFold(dependencyEcosystems, ecosystem) {
+			l.add(yamlMappingValue(dependenciesNode, "ecosystems"), LintError, policy.ID, fmt.Sprintf("_dependencies.ecosystems[%d]", i), "unknown ecosystem %q (expected one of %s)", ecosystem, strings.Join(dependencyEcosystems, ","))
+		}
+	}
+	for _, section := range []struct {
+		field string
+		rules []DependencyRule
+	}{{"deny", config.Deny}, {"constraints", config.Constraints}} {
+		rulesNode := yamlMappingValue(dependenciesNode, section.field)
+		for i, rule := range section.rules {
+			field := fmt.Sprintf("_dependencies.%s[%d]", section.field, i)
+			ruleNode := rulesNode
+			if rulesNode != nil && i < len(rulesNode.Content) {
+				ruleNode = rulesNode.Content[i]
+			}
+			if rule.Name == "" {
+				l.add(ruleNode, LintError, policy.ID, field+".name", "rule without name")
+			}
+			if rule.Ecosystem != "" && !containsFold(dependencyEcosystems, rule.Ecosystem) {
+				l.add(yamlMappingValue(ruleNode, "ecosystem"), LintError, policy.ID, field+".ecosystem", "unknown ecosystem %q (expected one of %s)", rule.Ecosystem, strings.Join(dependencyEcosystems, ","))
+			}
+			if rule.Version == "" && section.field == "constraints" {
+				l.add(ruleNode, LintError, policy.ID, field+".version", "constraints require a version")
+			} else if rule.Version != "" {
+				if _, err := parseVersionConstraint(rule.Version); err != nil {
+					l.add(yamlMappingValue(ruleNode, "version"), LintError, policy.ID, field+".version", "%v", err)
+				}
+			}
+			if _, ok := parsePatternSeverity(rule.Severity); rule.Severity != "" && !ok {
+				l.add(yamlMappingValue(ruleNode, "severity"), LintError, policy.ID, field+".severity", "unknown severity %q (expected one of %s)", rule.Severity, strings.Join(patternSeverities, ","))
+			}
+		}
+	}
+	if _, ok := parsePatternSeverity(config.Licenses.Severity); config.Licenses.Severity != "" && !ok {
+		l.add(yamlMappingValue(yamlMappingValue(dependenciesNode, "licenses"), "severity"), LintError, policy.ID, "_dependencies.licenses.severity", "unknown severity %q (expected one of %s)", config.Licenses.Severity, strings.Join(patternSeverities, ","))
+	}
+	if config.Advisories != "" {
+		if _, err := loadDependencyAdvisories(config.Advisories); err != nil {
+			l.add(yamlMappingValue(dependenciesNode, "advisories"), LintError, policy.ID, "_dependencies.advisories", "%v", err)
+		}
+	}
+}
+
+func (l *policyLinter) lintDocumentSelector(policy Policy, documentsNode *yaml.Node) {
+	if policy.Type != "yml" {
+		l.add(documentsNode, LintWarning, policy.ID, "_schema.documents", "documents is only used by yml policies")
+		return
+	}
+	if policy.Schema.Patch {
+		l.add(documentsNode, LintWarning, policy.ID, "_schema.documents", "documents is ignored when patch is set")
+	}
+	if policyFormat(policy, "yaml") != "yaml" {
+		l.add(documentsNode, LintWarning, policy.ID, "_schema.documents", "documents is ignored when format is not yaml")
+	}
+	for _, list := range []struct {
+		field    string
+		patterns []string
+	}{{"api_version", policy.Schema.Documents.APIVersion}, {"kind", policy.Schema.Documents.Kind}} {
+		for i, pattern := range list.patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				l.add(yamlMappingValue(documentsNode, list.field), LintError, policy.ID, fmt.Sprintf("_schema.documents.%s[%d]", list.field, i), "invalid pattern %q: %v", pattern, err)
+			}
+		}
+	}
+}
+
+func (l *policyLinter) lintLicense(policy Policy, licenseNode, policyNode *yaml.Node) {
+	config := policy.License
+	if len(config.Allow) == 0 && len(config.Deny) == 0 && len(config.Review) == 0 {
+		l.add(policyNode, LintError, policy.ID, "_license", "license policies require allow, deny or review lists")
+	}
+	if config.Unknown != "" && !containsFold(licenseUnknownModes, config.Unknown) {
+		l.add(yamlMappingValue(licenseNode, "unknown"), LintError, policy.ID, "_license.unknown", "unknown mode %q (expected one of %s)", config.Unknown, strings.Join(licenseUnknownModes, ","))
+	}
+	if config.HeaderLines < 0 {
+		l.add(yamlMappingValue(licenseNode, "header_lines"), LintError, policy.ID, "_license.header_lines", "header_lines must not be negative")
+	}
+	for _, list := range []struct {
+		field string
+		ids   []string
+	}{{"allow", config.Allow}, {"deny", config.Deny}, {"review", config.Review}} {
+		for i, id := range list.ids {
+			if _, err := path.Match(id, ""); err != nil {
+				l.add(yamlMappingValue(licenseNode, list.field), LintError, policy.ID, fmt.Sprintf("_license.%s[%d]", list.field, i), "invalid pattern %q: %v", id, err)
+			}
+		}
+	}
+}
+
+func (l *policyLinter) lintEnforcement(policy Policy, policyNode *yaml.Node) {
+	node := yamlMappingValue(policyNode, "enforcement")
+	if len(policy.Enforcement) == 0 {
+		l.add(policyNode, LintWarning, policy.ID, "enforcement", "no enforcement rules, findings default to warning level")
+		return
+	}
+	for i, rule := range policy.Enforcement {
+		ruleNode := node
+		if node != nil && i < len(node.Content) {
+			ruleNode = node.Content[i]
+		}
+		field := fmt.Sprintf("enforcement[%d]", i)
+		if rule.Environment == "" {
+			l.add(ruleNode, LintWarning, policy.ID, field+".environment", "enforcement rule without environment only applies as a fallback")
+		}
+		for key, value := range map[string]string{"fatal": rule.Fatal, "exceptions": rule.Exceptions} {
+			if value != "" && value != "true" && value != "false" {
+				l.add(yamlMappingValue(ruleNode, key), LintWarning, policy.ID, field+"."+key, "expected \"true\" or \"false\", %q is treated as false", value)
+			}
+		}
+		if rule.Confidence != "" && !containsFold(lintConfidences, rule.Confidence) {
+			l.add(yamlMappingValue(ruleNode, "confidence"), LintError, policy.ID, field+".confidence", "unknown confidence %q (expected one of %s)", rule.Confidence, strings.Join(lintConfidences, ","))
+		}
+	}
+}
+
+func (l *policyLinter) lintRego(policy Policy, regoNode, policyNode *yaml.Node, dataRequired bool) {
+	id := policy.ID
+	if regoNode == nil {
+		l.add(policyNode, LintError, id, "_rego", "rego policies require a _rego section")
+		return
+	}
+	for key, value := range map[string]string{"policy_file": policy.Rego.PolicyFile, "policy_query": policy.Rego.PolicyQuery, "policy_data": policy.Rego.PolicyData} {
+		if value == "" && (key != "policy_data" || dataRequired) {
+			l.add(regoNode, LintError, id, "_rego."+key, "_rego.%s is required", key)
+		}
+	}
+
+	if policy.Rego.PolicyData != "" {
+		if _, err := readJSONFile(policy.Rego.PolicyData); err != nil {
+			l.add(yamlMappingValue(regoNode, "policy_data"), LintError, id, "_rego.policy_data", "policy data is not readable JSON: %v", err)
+		}
+	}
+
+	if policy.Rego.PolicyFile == "" {
+		return
+	}
+	fileNode := yamlMappingValue(regoNode, "policy_file")
+	content, err := os.ReadFile(policy.Rego.PolicyFile)
+	if err != nil {
+		l.add(fileNode, LintError, id, "_rego.policy_file", "rego file not readable relative to the working directory: %v", err)
+		return
+	}
+
+	module, err := ast.ParseModule(policy.Rego.PolicyFile, string(content))
+	if err != nil {
+		l.add(fileNode, LintError, id, "_rego.policy_file", "rego parse error: %v", err)
+		return
+	}
+	compiler := ast.NewCompiler()
+	if compiler.Compile(map[string]*ast.Module{policy.Rego.PolicyFile: module}); compiler.Failed() {
+		l.add(fileNode, LintError, id, "_rego.policy_file", "rego compile error: %v", compiler.Errors)
+		return
+	}
+
+	if policy.Rego.PolicyQuery != "" {
+		packageName, err := extractPackageName(string(content))
+		if err != nil {
+			l.add(fileNode, LintError, id, "_rego.policy_file", "%v", err)
+			return
+		}
+		if queryPackage := extractQueryPackage(policy.Rego.PolicyQuery); queryPackage != packageName {
+			l.add(yamlMappingValue(regoNode, "policy_query"), LintError, id, "_rego.policy_query", "query package %q does not match rego package %q", queryPackage, packageName)
+		}
+	}
+}
+
+// checkCUE compiles a CUE structure and maps CUE error positions back to the YAML lines of the block
+func (l *policyLinter) checkCUE(structure string, node *yaml.Node, policyID string) {
+	value := cuecontext.New().CompileString(structure)
+	if value.Err() == nil {
+		return
+	}
+	for _, e := range cueerrors.Errors(value.Err()) {
+		issueNode := node
+		if node != nil && (node.Style == yaml.LiteralStyle || node.Style == yaml.FoldedStyle) {
+			if pos := e.Position(); pos.IsValid() {
+				shifted := *node
+				shifted.Line = node.Line + pos.Line()
+				shifted.Column = 0
+				issueNode = &shifted
+			}
+		}
+		l.add(issueNode, LintError, policyID, "_schema.structure", "CUE compile error: %v", e)
+	}
+}
+
+func (l *policyLinter) checkURL(endpoint string, parent *yaml.Node, key, policyID, field string) {
+	if endpoint == "" {
+		l.add(parent, LintError, policyID, field, "endpoint is required")
+		return
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		l.add(yamlMappingValue(parent, key), LintError, policyID, field, "invalid endpoint URL %q", endpoint)
+	}
+}
+
+func (l *policyLinter) checkAuth(auth map[string]string, node *yaml.Node, policyID, field string) {
+	if len(auth) == 0 {
+		return
+	}
+	authType, ok := auth["type"]
+	if !ok {
+		l.add(node, LintWarning, policyID, field+".type", "auth without type is ignored")
+		return
+	}
+	required := map[string][]string{
+		"basic":         {"username_env", "password_env"},
+		"bearer":        {"token_env"},
+		"header":        {"header", "key_env"},
+		"authorization": {"prefix", "key_env"},
+	}
+	keys, known := required[authType]
+	if !known {
+		l.add(yamlMappingValue(node, "type"), LintError, policyID, field+".type", "unsupported auth type %q (expected one of %s)", authType, strings.Join(lintAuthTypes, ","))
+		return
+	}
+	for _, key := range keys {
+		if auth[key] == "" {
+			l.add(node, LintError, policyID, field+"."+key, "%s auth requires %s", authType, key)
+		}
+	}
+}
+
+// lintIgnorePatterns checks a list of gitignore patterns
+func (l *policyLinter) lintIgnorePatterns(node *yaml.Node, policyID, field string) {
+	for _, item := range node.Content {
+		if _, _, err := parseIgnorePattern("", item.Value); err != nil {
+			l.add(item, LintError, policyID, field, "%v", err)
+		}
+	}
+}
+
+// checkUnknownKeys warns about mapping keys that do not match any yaml field of the target type (typos are silently ignored otherwise)
+func (l *policyLinter) checkUnknownKeys(node *yaml.Node, typ reflect.Type, policyID, field string) {
+	if node == nil || node.Kind != yaml.MappingNode || typ.Kind() != reflect.Struct {
+		return
+	}
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < typ.NumField(); i++ {
+		tag := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		fields[tag] = typ.Field(i).Type
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		path := key.Value
+		if field != "" {
+			path = field + "." + key.Value
+		}
+		fieldType, ok := fields[key.Value]
+		if !ok {
+			l.add(key, LintWarning, policyID, path, "unknown field %q is ignored", key.Value)
+			continue
+		}
+		switch {
+		case fieldType.Kind() == reflect.Struct:
+			l.checkUnknownKeys(value, fieldType, policyID, path)
+		case fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Struct && value.Kind == yaml.SequenceNode && fieldType.Elem() != reflect.TypeOf(HookConfig{}):
+			for j, item := range value.Content {
+				l.checkUnknownKeys(item, fieldType.Elem(), policyID, fmt.Sprintf("%s[%d]", path, j))
+			}
+		}
+	}
+}
+
+// compilePCRE2 validates a pattern with the same engine and flags used by scans (ripgrep --pcre2),
+// falling back to Go regexp syntax when ripgrep is not available, in which case errors are not verified
+func compilePCRE2(pattern string) (bool, error) {
+	if rgPath != "" {
+		cmd := exec.Command(rgPath, "--pcre2", "--quiet", "-e", pattern, "-")
+		cmd.Stdin = strings.NewReader("")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+			return true, nil // no match on empty input, the pattern compiled
+		case errors.As(err, &exitErr):
+			return true, fmt.Errorf("invalid PCRE2 regex: %s", strings.TrimSpace(stderr.String()))
+		}
+		log.Debug().Err(err).Msg("ripgrep unavailable for regex validation, falling back to Go regexp")
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return false, fmt.Errorf("ripgrep unavailable, Go regexp rejects the pattern (may still be valid PCRE2): %v", err)
+	}
+	return false, nil
+}
+
+// yamlMappingValue returns the value node of a key in a mapping node
+func yamlMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func lineOf(node *yaml.Node) int {
+	if node == nil {
+		return 0
+	}
+	return node.Line
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedPolicyTypes() []string {
+	types := make([]string, 0, len(policyTypeRequirements))
+	for t := range policyTypeRequirements {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
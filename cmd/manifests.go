@@ -0,0 +1,583 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ecosystemNPM   = "npm"
+	ecosystemGo    = "go"
+	ecosystemPyPI  = "pypi"
+	ecosystemMaven = "maven"
+)
+
+var dependencyEcosystems = []string{ecosystemGo, ecosystemMaven, ecosystemNPM, ecosystemPyPI}
+
+// dependencyPackage is a package declared by a manifest or lockfile, Version is empty when only a range is declared
+type dependencyPackage struct {
+	Ecosystem string
+	Name      string
+	Version   string
+	Spec      string
+	Direct    bool
+	License   string
+	File      string
+	Line      int
+}
+
+// PURL returns the package URL identifying the package across tools
+func (p dependencyPackage) PURL() string {
+	name := p.Name
+	purlType := p.Ecosystem
+	switch p.Ecosystem {
+	case ecosystemGo:
+		purlType = "golang"
+	case ecosystemMaven:
+		name = strings.Replace(name, ":", "/", 1)
+	}
+	purl := fmt.Sprintf("pkg:%s/%s", purlType, name)
+	if p.Version != "" {
+		purl += "@" + p.Version
+	}
+	return purl
+}
+
+// manifestParsers are selected on the file name of each target file
+var manifestParsers = []struct {
+	match func(name string) bool
+	parse func(path string, content []byte) ([]dependencyPackage, error)
+}{
+	{func(name string) bool { return name == "package-lock.json" || name == "npm-shrinkwrap.json" }, parsePackageLock},
+	{func(name string) bool { return name == "package.json" }, parsePackageJSON},
+	{func(name string) bool { return name == "go.mod" }, parseGoMod},
+	{func(name string) bool { return name == "go.sum" }, parseGoSum},
+	{func(name string) bool {
+		return strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt")
+	}, parseRequirements},
+	{func(name string) bool { return name == "pom.xml" }, parsePomXML},
+}
+
+func isDependencyManifest(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	for _, parser := range manifestParsers {
+		if parser.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDependencyManifest returns the packages of a supported manifest, nil for other files
+func parseDependencyManifest(path string) ([]dependencyPackage, error) {
+	name := strings.ToLower(filepath.Base(path))
+	for _, parser := range manifestParsers {
+		if !parser.match(name) {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		packages, err := parser.parse(path, content)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", path, err)
+		}
+		return packages, nil
+	}
+	return nil, nil
+}
+
+// parseJSONNode parses JSON through yaml.v3 to keep the line of every key
+func parseJSONNode(content []byte) (*yaml.Node, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	return document.Content[0], nil
+}
+
+// yamlMappingPairs iterates over the keys and values of a mapping node
+func yamlMappingPairs(node *yaml.Node, fn func(key, value *yaml.Node)) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		fn(node.Content[i], node.Content[i+1])
+	}
+}
+
+func yamlScalar(node *yaml.Node, key string) string {
+	if value := yamlMappingValue(node, key); value != nil && value.Kind == yaml.ScalarNode {
+		return value.Value
+	}
+	return ""
+}
+
+// parsePackageLock reads npm lockfiles, v2 and v3 "packages" or v1 nested "dependencies"
+func parsePackageLock(path string, content []byte) ([]dependencyPackage, error) {
+	root, err := parseJSONNode(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var packages []dependencyPackage
+	if packagesNode := yamlMappingValue(root, "packages"); packagesNode != nil {
+		direct := make(map[string]bool)
+		rootPackage := yamlMappingValue(packagesNode, "")
+		for _, field := range []string{"dependencies", "devDependencies", "optionalDependencies", "peerDependencies"} {
+			yamlMappingPairs(yamlMappingValue(rootPackage, field), func(key, _ *yaml.Node) {
+				direct[key.Value] = true
+			})
+		}
+
+		yamlMappingPairs(packagesNode, func(key, value *yaml.Node) {
+			index := strings.LastIndex(key.Value, "node_modules/")
+			if index < 0 || yamlScalar(value, "link") == "true" {
+				return
+			}
+			name := key.Value[index+len("node_modules/"):]
+			packages = append(packages, dependencyPackage{
+				Ecosystem: ecosystemNPM,
+				Name:      name,
+				Version:   yamlScalar(value, "version"),
+				Direct:    direct[name] && key.Value == "node_modules/"+name,
+				License:   yamlScalar(value, "license"),
+				File:      path,
+				Line:      key.Line,
+			})
+		})
+		return packages, nil
+	}
+
+	// lockfile v1 does not tell direct dependencies apart
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		yamlMappingPairs(node, func(key, value *yaml.Node) {
+			packages = append(packages, dependencyPackage{
+				Ecosystem: ecosystemNPM,
+				Name:      key.Value,
+				Version:   yamlScalar(value, "version"),
+				File:      path,
+				Line:      key.Line,
+			})
+			walk(yamlMappingValue(value, "dependencies"))
+		})
+	}
+	walk(yamlMappingValue(root, "dependencies"))
+	return packages, nil
+}
+
+// parsePackageJSON reads the declared dependencies of package.json, all direct
+func parsePackageJSON(path string, content []byte) ([]dependencyPackage, error) {
+	root, err := parseJSONNode(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var packages []dependencyPackage
+	for _, field := range []string{"dependencies", "devDependencies", "optionalDependencies", "peerDependencies"} {
+		yamlMappingPairs(yamlMappingValue(root, field), func(key, value *yaml.Node) {
+			packages = append(packages, dependencyPackage{
+				Ecosystem: ecosystemNPM,
+				Name:      key.Value,
+				Version:   exactVersion(value.Value),
+				Spec:      value.Value,
+				Direct:    true,
+				File:      path,
+				Line:      key.Line,
+			})
+		})
+	}
+	return packages, nil
+}
+
+// parseGoMod reads require directives, modules marked "// indirect" are transitive
+func parseGoMod(path string, content []byte) ([]dependencyPackage, error) {
+	var packages []dependencyPackage
+	inRequire := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		indirect := strings.Contains(line, "// indirect")
+		if index := strings.Index(line, "//"); index >= 0 {
+			line = strings.TrimSpace(line[:index])
+		}
+
+		switch {
+		case line == "require (":
+			inRequire = true
+			continue
+		case inRequire && line == ")":
+			inRequire = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case !inRequire:
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		packages = append(packages, dependencyPackage{
+			Ecosystem: ecosystemGo,
+			Name:      strings.Trim(fields[0], `"`),
+			Version:   fields[1],
+			Direct:    !indirect,
+			File:      path,
+			Line:      lineNumber,
+		})
+	}
+	return packages, scanner.Err()
+}
+
+// parseGoSum reads the module checksums, go.sum does not tell direct dependencies apart
+func parseGoSum(path string, content []byte) ([]dependencyPackage, error) {
+	var packages []dependencyPackage
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		key := fields[0] + "@" + fields[1]
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		packages = append(packages, dependencyPackage{
+			Ecosystem: ecosystemGo,
+			Name:      fields[0],
+			Version:   fields[1],
+			File:      path,
+			Line:      lineNumber,
+		})
+	}
+	return packages, scanner.Err()
+}
+
+var (
+	requirementLine   = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(?:\[[^\]]*\])?\s*(.*)$`)
+	pypiNameSeparator = regexp.MustCompile(`[-_.]+`)
+	pomPropertyRef    = regexp.MustCompile(`\$\{([^}]+)\}`)
+)
+
+// parseRequirements reads pip requirement files, only name==version pins have a version
+func parseRequirements(path string, content []byte) ([]dependencyPackage, error) {
+	var packages []dependencyPackage
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if index := strings.Index(line, " #"); index >= 0 {
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		if index := strings.Index(line, ";"); index >= 0 {
+			line = strings.TrimSpace(line[:index])
+		}
+		match := requirementLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		spec := strings.ReplaceAll(match[2], " ", "")
+		version := ""
+		if strings.HasPrefix(spec, "==") && !strings.ContainsAny(spec, ",*") {
+			version = strings.TrimPrefix(spec, "==")
+		}
+		packages = append(packages, dependencyPackage{
+			Ecosystem: ecosystemPyPI,
+			Name:      normalizePyPIName(match[1]),
+			Version:   version,
+			Spec:      spec,
+			Direct:    true,
+			File:      path,
+			Line:      lineNumber,
+		})
+	}
+	return packages, scanner.Err()
+}
+
+// normalizePyPIName applies the PEP 503 normalization
+func normalizePyPIName(name string) string {
+	return pypiNameSeparator.ReplaceAllString(strings.ToLower(name), "-")
+}
+
+// parsePomXML reads the dependencies of a Maven pom, ${property} versions are resolved from <properties>
+func parsePomXML(path string, content []byte) ([]dependencyPackage, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	properties := make(map[string]string)
+
+	var packages []dependencyPackage
+	var stack []string
+	var current *dependencyPackage
+	var groupID, artifactID, version, scope string
+	var text strings.Builder
+
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, element.Name.Local)
+			text.Reset()
+			if element.Name.Local == "dependency" {
+				current = &dependencyPackage{Ecosystem: ecosystemMaven, Direct: true, File: path, Line: 1 + bytes.Count(content[:offset], []byte("\n"))}
+				groupID, artifactID, version, scope = "", "", "", ""
+			}
+		case xml.CharData:
+			text.Write(element)
+		case xml.EndElement:
+			value := strings.TrimSpace(text.String())
+			parent := ""
+			if len(stack) > 1 {
+				parent = stack[len(stack)-2]
+			}
+			switch {
+			case parent == "properties":
+				properties[element.Name.Local] = value
+			case current != nil && parent == "dependency":
+				switch element.Name.Local {
+				case "groupId":
+					groupID = value
+				case "artifactId":
+					artifactID = value
+				case "version":
+					version = value
+				case "scope":
+					scope = value
+				}
+			case current != nil && element.Name.Local == "dependency":
+				current.Name = groupID + ":" + artifactID
+				current.Spec = version
+				current.Direct = scope != "import"
+				packages = append(packages, *current)
+				current = nil
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			text.Reset()
+		}
+	}
+
+	for i := range packages {
+		spec := pomPropertyRef.ReplaceAllStringFunc(packages[i].Spec, func(ref string) string {
+			if value, ok := properties[ref[2:len(ref)-1]]; ok {
+				return value
+			}
+			return ref
+		})
+		packages[i].Spec = spec
+		packages[i].Version = exactVersion(spec)
+	}
+	return packages, nil
+}
+
+// exactVersion returns a declared version when it pins a single version, "" for ranges
+func exactVersion(spec string) string {
+	spec = strings.TrimPrefix(strings.TrimSpace(spec), "=")
+	if spec == "" || strings.ContainsAny(spec, "^~<>*|, []()$xX") {
+		return ""
+	}
+	if _, ok := parseVersion(spec); !ok {
+		return ""
+	}
+	return spec
+}
+
+// version is a dotted numeric version with an optional pre-release, "v" prefixes and build metadata are ignored
+type version struct {
+	numbers    []int
+	prerelease string
+}
+
+var mavenReleaseQualifiers = []string{"RELEASE", "Final", "GA"}
+
+// parseVersion reads dotted numbers, a "-", "_" or Maven "." qualifier is a pre-release
+func parseVersion(value string) (version, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "v")
+	if index := strings.IndexByte(value, '+'); index >= 0 {
+		value = value[:index]
+	}
+	var v version
+	if index := strings.IndexAny(value, "-_"); index >= 0 {
+		v.prerelease = value[index+1:]
+		value = value[:index]
+	}
+	if value == "" {
+		return v, false
+	}
+	parts := strings.Split(value, ".")
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			// Maven qualifiers follow the numbers after a dot : 5.3.20.RELEASE, 2.0.0.M1
+			if i == 0 || v.prerelease != "" {
+				return v, false
+			}
+			v.prerelease = strings.Join(parts[i:], ".")
+			break
+		}
+		v.numbers = append(v.numbers, number)
+	}
+	// Maven release qualifiers name the release itself
+	if containsFold(mavenReleaseQualifiers, v.prerelease) {
+		v.prerelease = ""
+	}
+	return v, true
+}
+
+// compareVersions orders versions numerically, a pre-release sorts before its release
+func compareVersions(a, b version) int {
+	for i := 0; i < max(len(a.numbers), len(b.numbers)); i++ {
+		var x, y int
+		if i < len(a.numbers) {
+			x = a.numbers[i]
+		}
+		if i < len(b.numbers) {
+			y = b.numbers[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case a.prerelease == b.prerelease:
+		return 0
+	case a.prerelease == "":
+		return 1
+	case b.prerelease == "":
+		return -1
+	case a.prerelease < b.prerelease:
+		return -1
+	}
+	return 1
+}
+
+// versionConstraint is a set of alternatives ("||") of comparators that must all hold (" " or ",")
+type versionConstraint [][]versionComparator
+
+type versionComparator struct {
+	operator string
+	version  version
+}
+
+var versionOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+func parseVersionConstraint(constraint string) (versionConstraint, error) {
+	var parsed versionConstraint
+	for _, alternative := range strings.Split(constraint, "||") {
+		var comparators []versionComparator
+		fields := strings.FieldsFunc(alternative, func(r rune) bool { return r == ',' || r == ' ' })
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			operator := "="
+			for _, candidate := range versionOperators {
+				if strings.HasPrefix(field, candidate) {
+					operator = candidate
+					field = field[len(candidate):]
+					break
+				}
+			}
+			// allow a space between the operator and the version
+			if field == "" && i+1 < len(fields) {
+				i++
+				field = fields[i]
+			}
+			v, ok := parseVersion(field)
+			if !ok {
+				return nil, fmt.Errorf("invalid version %q in constraint %q", field, constraint)
+			}
+			if operator == "==" {
+				operator = "="
+			}
+			comparators = append(comparators, versionComparator{operator: operator, version: v})
+		}
+		if len(comparators) == 0 {
+			return nil, fmt.Errorf("empty version constraint %q", constraint)
+		}
+		parsed = append(parsed, comparators)
+	}
+	return parsed, nil
+}
+
+// matches reports whether a version satisfies the constraint, unparsable versions never do
+func (c versionConstraint) matches(value string) bool {
+	v, ok := parseVersion(value)
+	if !ok {
+		return false
+	}
+	for _, comparators := range c {
+		all := true
+		for _, comparator := range comparators {
+			cmp := compareVersions(v, comparator.version)
+			switch comparator.operator {
+			case "=":
+				all = cmp == 0
+			case "!=":
+				all = cmp != 0
+			case ">":
+				all = cmp > 0
+			case ">=":
+				all = cmp >= 0
+			case "<":
+				all = cmp < 0
+			case "<=":
+				all = cmp <= 0
+			}
+			if !all {
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// sortDependencyPackages orders packages by file and line for stable reports
+func sortDependencyPackages(packages []dependencyPackage) {
+	sort.SliceStable(packages, func(i, j int) bool {
+		if packages[i].File != packages[j].File {
+			return packages[i].File < packages[j].File
+		}
+		return packages[i].Line < packages[j].Line
+	})
+}
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	engineRipgrep = "ripgrep"
+	engineNative  = "native"
+
+	// nativeEngineVersion changes whenever the native engine can produce different results for the same input
+	nativeEngineVersion = "2"
+
+	// nativeBinaryProbe is the number of leading bytes checked for NUL, like ripgrep's binary detection
+	nativeBinaryProbe = 8192
+)
+
+var scanEngine string
+
+// resolveScanEngine applies the policy file engine when the flag is not set and validates the result
+func resolveScanEngine(config Config) error {
+	if scanEngine == "" {
+		scanEngine = config.Flags.Engine
+	}
+	scanEngine = strings.ToLower(strings.TrimSpace(scanEngine))
+	switch scanEngine {
+	case "":
+		scanEngine = engineRipgrep
+	case engineRipgrep, engineNative:
+	default:
+		return fmt.Errorf("invalid engine %q (expected native or ripgrep)", scanEngine)
+	}
+	log.Debug().Str("engine", scanEngine).Msg("Regex engine selected")
+	return nil
+}
+
+func useNativeEngine() bool {
+	return scanEngine == engineNative
+}
+
+// compileNativePatterns compiles each policy pattern the way ripgrep does with -i and -U :
+// case-insensitive, ^ and $ match at line boundaries and matches may span lines.
+// Empty patterns are nil so the index of each regexp stays the index of its _regex entry.
+func compileNativePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	searched := 0
+	for i, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?im)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q is not supported by the native engine, use --engine ripgrep: %w", pattern, err)
+		}
+		compiled[i] = re
+		searched++
+	}
+	if searched == 0 {
+		return nil, fmt.Errorf("no patterns to search")
+	}
+	return compiled, nil
+}
+
+// wholeTargetFiles narrows the files of the target walk to the ones searched without a filepattern,
+// hidden files and directories are skipped like ripgrep does
+func wholeTargetFiles(targetDir string, filePaths []string) []string {
+	files := make([]string, 0, len(filePaths))
+	for _, file := range filePaths {
+		if !isHiddenTargetPath(targetDir, file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// isHiddenTargetPath reports whether a file or one of its directories below the target is hidden
+func isHiddenTargetPath(targetDir, path string) bool {
+	rel, err := filepath.Rel(targetDir, path)
+	if err != nil {
+		rel = path
+	}
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(part, ".") && part != "." && part != ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// searchesWholeTarget reports whether a scan searches the target directory rather than the given files
+func searchesWholeTarget(policy Policy) bool {
+	return policy.FilePattern == "" && len(policy.Include) == 0 && !incrementalAudit && !policy.explicitFiles
+}
+
+// ripgrep --json message types produced by the native engine, consumed by patchJSONOutputFile and the SARIF generators
+type nativeRGText struct {
+	Text string `json:"text"`
+}
+
+type nativeRGSubmatch struct {
+	Match   nativeRGText `json:"match"`
+	Start   int          `json:"start"`
+	End     int          `json:"end"`
+	Pattern *int         `json:"pattern,omitempty"`
+}
+
+type nativeRGData struct {
+	Path           *nativeRGText      `json:"path,omitempty"`
+	Lines          *nativeRGText      `json:"lines,omitempty"`
+	LineNumber     *int               `json:"line_number,omitempty"`
+	AbsoluteOffset *int               `json:"absolute_offset,omitempty"`
+	Submatches     []nativeRGSubmatch `json:"submatches,omitempty"`
+	Stats          *nativeRGStats     `json:"stats,omitempty"`
+	ElapsedTotal   *nativeRGElapsed   `json:"elapsed_total,omitempty"`
+}
+
+type nativeRGStats struct {
+	Elapsed           nativeRGElapsed `json:"elapsed"`
+	Searches          int             `json:"searches"`
+	SearchesWithMatch int             `json:"searches_with_match"`
+	BytesSearched     int64           `json:"bytes_searched"`
+	BytesPrinted      int64           `json:"bytes_printed"`
+	MatchedLines      int             `json:"matched_lines"`
+	Matches           int             `json:"matches"`
+}
+
+type nativeRGElapsed struct {
+	Secs  int64  `json:"secs"`
+	Nanos int    `json:"nanos"`
+	Human string `json:"human"`
+}
+
+type nativeRGMessage struct {
+	Type string       `json:"type"`
+	Data nativeRGData `json:"data"`
+}
+
+// nativeSearch matches each pattern against each file and writes ripgrep compatible JSON lines,
+// so the SARIF generators produce the same results for both engines. It reports whether anything matched.
+func nativeSearch(patterns []string, files []string, writer io.Writer) (bool, error) {
+	compiled, err := compileNativePatterns(patterns)
+	if err != nil {
+		return false, err
+	}
+
+	start := time.Now()
+	stats := nativeRGStats{}
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Debug().Err(err).Str("file", file).Msg("native engine skipped unreadable file")
+			continue
+		}
+		stats.Searches++
+		stats.BytesSearched += int64(len(content))
+		if bytes.IndexByte(content[:min(len(content), nativeBinaryProbe)], 0) >= 0 {
+			continue
+		}
+
+		var messages []nativeRGMessage
+		for i, re := range compiled {
+			if re != nil {
+				messages = append(messages, nativeMatchMessages(re, i, file, content)...)
+			}
+		}
+		if len(messages) == 0 {
+			continue
+		}
+		stats.SearchesWithMatch++
+
+		path := &nativeRGText{Text: file}
+		if err := encoder.Encode(nativeRGMessage{Type: "begin", Data: nativeRGData{Path: path}}); err != nil {
+			return false, err
+		}
+		for _, message := range messages {
+			stats.MatchedLines += strings.Count(message.Data.Lines.Text, "\n")
+			stats.Matches += len(message.Data.Submatches)
+			if err := encoder.Encode(message); err != nil {
+				return false, err
+			}
+		}
+		if err := encoder.Encode(nativeRGMessage{Type: "end", Data: nativeRGData{Path: path}}); err != nil {
+			return false, err
+		}
+	}
+
+	elapsed := time.Since(start)
+	stats.Elapsed = nativeElapsed(elapsed)
+	total := nativeElapsed(elapsed)
+	if err := encoder.Encode(nativeRGMessage{Type: "summary", Data: nativeRGData{Stats: &stats, ElapsedTotal: &total}}); err != nil {
+		return false, err
+	}
+
+	if w, ok := writer.(*bufio.Writer); ok {
+		if err := w.Flush(); err != nil {
+			return false, err
+		}
+	}
+
+	return stats.Matches > 0, nil
+}
+
+// nativeMatchMessages groups matches sharing lines into a single match message, as ripgrep does in multiline mode.
+// Submatches carry the index of the pattern that produced them.
+func nativeMatchMessages(re *regexp.Regexp, pattern int, file string, content []byte) []nativeRGMessage {
+	var messages []nativeRGMessage
+	groupStart, groupEnd := -1, -1
+	var submatches [][]int
+
+	flush := func() {
+		if groupStart < 0 {
+			return
+		}
+		lineNumber := bytes.Count(content[:groupStart], []byte("\n")) + 1
+		offset := groupStart
+		message := nativeRGMessage{Type: "match", Data: nativeRGData{
+			Path:           &nativeRGText{Text: file},
+			Lines:          &nativeRGText{Text: string(content[groupStart:groupEnd])},
+			LineNumber:     &lineNumber,
+			AbsoluteOffset: &offset,
+		}}
+		for _, m := range submatches {
+			message.Data.Submatches = append(message.Data.Submatches, nativeRGSubmatch{
+				Match:   nativeRGText{Text: string(content[m[0]:m[1]])},
+				Start:   m[0] - groupStart,
+				End:     m[1] - groupStart,
+				Pattern: &pattern,
+			})
+		}
+		messages = append(messages, message)
+		groupStart, groupEnd, submatches = -1, -1, nil
+	}
+
+	for _, m := range re.FindAllIndex(content, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		lineStart := bytes.LastIndexByte(content[:m[0]], '\n') + 1
+		lineEnd := len(content)
+		last := m[1] - 1
+		if content[last] == '\n' {
+			lineEnd = m[1]
+		} else if i := bytes.IndexByte(content[m[1]:], '\n'); i >= 0 {
+			lineEnd = m[1] + i + 1
+		}
+
+		if groupStart >= 0 && lineStart < groupEnd {
+			groupEnd = max(groupEnd, lineEnd)
+		} else {
+			flush()
+			groupStart, groupEnd = lineStart, lineEnd
+		}
+		submatches = append(submatches, m)
+	}
+	flush()
+
+	return messages
+}
+
+func nativeElapsed(d time.Duration) nativeRGElapsed {
+	return nativeRGElapsed{
+		Secs:  int64(d / time.Second),
+		Nanos: int(d % time.Second),
+		Human: fmt.Sprintf("%.6fs", d.Seconds()),
+	}
+}
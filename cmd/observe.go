@@ -26,7 +26,7 @@ var (
 	observeTagsAll      string
 	observeEnvironment  string
 	observeEnvDetection bool
-	observePolicyFile   string
+	observePolicyFiles  []string
 	observeSchedule     string
 	observeReport       string
 	observeMode         string
@@ -56,7 +56,8 @@ func init() {
 	observeCmd.Flags().StringVar(&observeTagsAll, "tags_all", "", "Filter policies that match all of the provided tags (comma-separated)")
 	observeCmd.Flags().StringVar(&observeEnvironment, "environment", "", "Filter policies that match the specified environment")
 	observeCmd.Flags().BoolVar(&observeEnvDetection, "env-detection", false, "Enable environment detection if no environment is specified")
-	observeCmd.Flags().StringVar(&observePolicyFile, "policy", "", "Policy file")
+	observeCmd.Flags().StringArrayVar(&observePolicyFiles, "policy", nil, "Policy <FILEPATH>, <DIRECTORY> or <URL>, repeat to layer overlays")
+	observeCmd.Flags().StringVar(&scanEngine, "engine", "", "Regex engine for scan and assure policies : native,ripgrep (default ripgrep)")
 	observeCmd.Flags().StringVar(&observeSchedule, "schedule", "", "Global Cron Schedule")
 	observeCmd.Flags().StringVar(&observeReport, "report", "", "Report Cron Schedule")
 	observeCmd.Flags().StringVar(&observeMode, "mode", "last", "Observe mode for path monitoring : first,last,all ")
@@ -71,22 +72,13 @@ func runObserve(cmd *cobra.Command, args []string) {
 
 	perf := Performance{StartTime: time.Now()}
 
-	sourceType, processedInput, err := DeterminePolicySource(observePolicyFile)
+	var err error
+	policyData, err = LoadPolicySources(observePolicyFiles, policyFileSHA256)
 	if err != nil {
-		log.Fatal().Err(err)
+		log.Fatal().Err(err).Strs("policy", observePolicyFiles).Msg("Error loading policy file")
 	}
-
-	switch sourceType {
-	case LocalFile:
-		policyData, err = LoadPolicyFile(processedInput)
-	case RemoteURL:
-		policyData, err = LoadRemotePolicy(processedInput, policyFileSHA256)
-	default:
-		log.Fatal().Msg("unknown policy source type")
-	}
-
-	if err != nil {
-		log.Fatal().Err(err).Str("file", observePolicyFile).Msg("Error loading policy file")
+	if err := resolveScanEngine(policyData.Config); err != nil {
+		log.Fatal().Err(err).Msg("Invalid engine")
 	}
 
 	// Clean up output directories
@@ -119,6 +111,14 @@ func runObserve(cmd *cobra.Command, args []string) {
 
 	observeConfig = GetConfig()
 
+	if err := LoadExceptions(policyData, observeConfig.Flags.ExceptionsFile); err != nil {
+		log.Fatal().Err(err).Msg("Error loading exceptions")
+	}
+
+	if err := LoadBaseline(observeConfig.Flags.Baseline); err != nil {
+		log.Fatal().Err(err).Msg("Error loading baseline")
+	}
+
 	if len(observeConfig.Hooks) > 0 {
 		if observeConfig.Flags.WebhookSecret != "" {
 			webhookSecret = os.Getenv(observeConfig.Flags.WebhookSecret)
@@ -203,7 +203,12 @@ func runObserve(cmd *cobra.Command, args []string) {
 			continue
 		}
 		if policy.Type != "api" && policy.Type != "runtime" && policy.Type != "rego" {
-			policy.Metadata.TargetInfo = preparePolicyPaths(policy, allFileInfos)
+			filePaths, err := preparePolicyPaths(policy, allFileInfos)
+			if err != nil {
+				log.Error().Err(err).Str("policy", policy.ID).Msg("Invalid file selection, skipping")
+				continue
+			}
+			policy.Metadata.TargetInfo = filePaths
 		}
 
 		if schedule != "" {
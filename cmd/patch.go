@@ -176,6 +176,7 @@ func applyPatches(jsonData, patches map[string]interface{}) {
 	}
 }
 func processGenericType(policy Policy, filePaths []string, fileType string) error {
+	fileType = policyFormat(policy, fileType)
 	var allResults []Result
 
 	// Create _patched directory if it doesn't exist
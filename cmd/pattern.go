@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// patternSeverities are the values accepted by the severity of a _regex entry
+var patternSeverities = []string{string(SARIFError), string(SARIFWarning), string(SARIFNote), string(SARIFNone)}
+
+// regexPatternStrings returns the raw patterns handed to the regex engines
+func regexPatternStrings(patterns []RegexPattern) []string {
+	result := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		result = append(result, pattern.Pattern)
+	}
+	return result
+}
+
+// patternMatcher attributes a match back to the _regex entry that produced it.
+// Each entry is searched on its own and the engines tag every submatch with the index of its entry.
+type patternMatcher struct {
+	patterns []RegexPattern
+}
+
+func newPatternMatcher(patterns []RegexPattern) *patternMatcher {
+	return &patternMatcher{patterns: patterns}
+}
+
+// match returns the entry at the index reported by the engine, nil when the output carries no valid index
+func (m *patternMatcher) match(index *int) *RegexPattern {
+	if index == nil {
+		if len(m.patterns) == 1 {
+			return &m.patterns[0]
+		}
+		return nil
+	}
+	if *index < 0 || *index >= len(m.patterns) {
+		return nil
+	}
+	return &m.patterns[*index]
+}
+
+// searchEachPattern runs a ripgrep search once per _regex entry and tags its matches with the entry index,
+// an alternation of all the patterns can't tell which one matched. It reports whether any search matched.
+func searchEachPattern(policy Policy, search func(patternFile string, writer *bufio.Writer) (bool, error), writer *bufio.Writer) (bool, error) {
+	matched := false
+	for i, pattern := range policy.Regex {
+		if pattern.Pattern == "" {
+			continue
+		}
+		patternFile, err := createSearchPatternFile([]string{pattern.Pattern}, fmt.Sprintf("%s_%d", NormalizeFilename(policy.ID), i))
+		if err != nil {
+			return matched, fmt.Errorf("error creating search pattern file: %w", err)
+		}
+
+		var output bytes.Buffer
+		buffered := bufio.NewWriter(&output)
+		found, err := search(patternFile, buffered)
+		os.Remove(patternFile)
+		if flushErr := buffered.Flush(); err == nil {
+			err = flushErr
+		}
+		if err != nil {
+			return matched, err
+		}
+		matched = matched || found
+
+		if err := tagPatternMatches(output.Bytes(), i, writer); err != nil {
+			return matched, err
+		}
+	}
+	return matched, nil
+}
+
+// tagPatternMatches copies ripgrep JSON lines to writer, adding the pattern index to the submatches of match messages
+func tagPatternMatches(output []byte, index int, writer io.Writer) error {
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var message map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		if err := decoder.Decode(&message); err == nil && message["type"] == "match" {
+			if data, ok := message["data"].(map[string]interface{}); ok {
+				submatches, _ := data["submatches"].([]interface{})
+				for _, submatch := range submatches {
+					if submatch, ok := submatch.(map[string]interface{}); ok {
+						submatch["pattern"] = index
+					}
+				}
+			}
+			if tagged, err := json.Marshal(message); err == nil {
+				line = tagged
+			}
+		}
+		if _, err := writer.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyPatternToResult adds the identity of the matched entry to a result and applies its overrides
+func applyPatternToResult(result *Result, pattern *RegexPattern) {
+	if pattern == nil {
+		return
+	}
+	result.Properties.PatternID = pattern.ID
+	if pattern.Description != "" {
+		result.Properties.Description = pattern.Description
+	}
+	if pattern.MsgSolution != "" {
+		result.Properties.MsgSolution = pattern.MsgSolution
+	}
+	if level, ok := parsePatternSeverity(pattern.Severity); ok {
+		result.Level = level
+		result.Properties.SarifInt = sarifLevelToInt(level)
+	}
+}
+
+func parsePatternSeverity(severity string) (SARIFLevel, bool) {
+	for _, level := range patternSeverities {
+		if strings.EqualFold(severity, level) {
+			return SARIFLevel(level), true
+		}
+	}
+	return "", false
+}
+
+// patternLabel names the matched entry in result messages
+func patternLabel(pattern *RegexPattern) string {
+	if pattern == nil {
+		return ""
+	}
+	switch {
+	case pattern.Description != "":
+		return fmt.Sprintf(" [%s]", pattern.Description)
+	case pattern.ID != "":
+		return fmt.Sprintf(" [%s]", pattern.ID)
+	}
+	return ""
+}
@@ -2,21 +2,21 @@ package cmd
 
 import (
 	"fmt"
-	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
 
-	"github.com/go-resty/resty/v2"
 	"gopkg.in/yaml.v3"
 )
 
 type PolicyFile struct {
-	Config     Config      `yaml:"Config"`
-	Version    string      `yaml:"Version"`
-	Namespace  string      `yaml:"Namespace"`
-	Policies   []Policy    `yaml:"Policies"`
-	SARIFRules []SARIFRule `json:"sarif_rules,omitempty"`
+	Include    []string         `yaml:"Include,omitempty"`
+	Config     Config           `yaml:"Config"`
+	Version    string           `yaml:"Version"`
+	Namespace  string           `yaml:"Namespace"`
+	Policies   []Policy         `yaml:"Policies"`
+	Overrides  []PolicyOverride `yaml:"Overrides,omitempty"`
+	SARIFRules []SARIFRule      `yaml:"-" json:"sarif_rules,omitempty"`
 }
 
 type Config struct {
@@ -35,6 +35,12 @@ type Config struct {
 		ReportSchedule string   `yaml:"report_schedule,omitempty"`
 		WebhookSecret  string   `yaml:"webhook_secret_env,omitempty"`
 		RemoteAuth     []string `yaml:"remote_auth,omitempty"`
+		FailOn         string   `yaml:"fail_on,omitempty"`
+		ExceptionsFile string   `yaml:"exceptions_file,omitempty"`
+		Baseline       string   `yaml:"baseline,omitempty"`
+		Engine         string   `yaml:"engine,omitempty"`
+		Redact         string   `yaml:"redact,omitempty"`
+		CacheDir       string   `yaml:"cache_dir,omitempty"`
 	} `yaml:"Flags,omitempty"`
 	Metadata struct {
 		HostOS          string `yaml:"host_os,omitempty"`
@@ -64,20 +70,37 @@ type HookConfig struct {
 }
 
 type Policy struct {
-	ID          string        `yaml:"id"`
-	InterceptID string        `yaml:"intercept_id,omitempty"`
-	RunID       string        `yaml:"intercept_run_id,omitempty"`
-	Schedule    string        `yaml:"schedule"`
-	Type        string        `yaml:"type"`
-	Enforcement []Enforcement `yaml:"enforcement"`
-	Metadata    Metadata      `yaml:"metadata"`
-	FilePattern string        `yaml:"filepattern"`
-	Observe     string        `yaml:"observe"`
-	Schema      Schema        `yaml:"_schema"`
-	Rego        Rego          `yaml:"_rego"`
-	Regex       []string      `yaml:"_regex"`
-	API         APIConfig     `yaml:"_api"`
-	Runtime     Runtime       `yaml:"_runtime"`
+	ID           string             `yaml:"id"`
+	InterceptID  string             `yaml:"intercept_id,omitempty"`
+	RunID        string             `yaml:"intercept_run_id,omitempty"`
+	Schedule     string             `yaml:"schedule"`
+	Type         string             `yaml:"type"`
+	Enforcement  []Enforcement      `yaml:"enforcement"`
+	Metadata     Metadata           `yaml:"metadata"`
+	FilePattern  string             `yaml:"filepattern"`
+	Include      []string           `yaml:"include,omitempty"`
+	Exclude      []string           `yaml:"exclude,omitempty"`
+	Ignore       []string           `yaml:"ignore,omitempty"`
+	MaxFileSize  string             `yaml:"max_file_size,omitempty"`
+	MaxDepth     int                `yaml:"max_depth,omitempty"`
+	SkipBinary   bool               `yaml:"skip_binary,omitempty"`
+	Observe      string             `yaml:"observe"`
+	Redact       string             `yaml:"redact,omitempty"`
+	ContextLines int                `yaml:"context_lines,omitempty"`
+	Format       string             `yaml:"format,omitempty"`
+	Schema       Schema             `yaml:"_schema"`
+	Rego         Rego               `yaml:"_rego"`
+	Regex        []RegexPattern     `yaml:"_regex"`
+	API          APIConfig          `yaml:"_api"`
+	Runtime      Runtime            `yaml:"_runtime"`
+	Secrets      SecretsConfig      `yaml:"_secrets"`
+	Dependencies DependenciesConfig `yaml:"_dependencies,omitempty"`
+	License      LicenseConfig      `yaml:"_license,omitempty"`
+	Exceptions   []Exception        `yaml:"exceptions,omitempty"`
+	Tests        []PolicyTest       `yaml:"tests,omitempty"`
+
+	// explicitFiles restricts scans to the given files even without filepattern (policy tests, result cache misses)
+	explicitFiles bool
 }
 
 type Enforcement struct {
@@ -98,10 +121,96 @@ type Metadata struct {
 	TargetInfo  []string `yaml:"target_info,omitempty"`
 }
 
+// RegexPattern is a _regex entry, written either as a plain pattern or as an object with its own identity
+type RegexPattern struct {
+	ID          string `yaml:"id,omitempty"`
+	Pattern     string `yaml:"pattern"`
+	Description string `yaml:"description,omitempty"`
+	Severity    string `yaml:"severity,omitempty"`
+	MsgSolution string `yaml:"msg_solution,omitempty"`
+	// Entropy and Checksum are only used by secrets policies
+	Entropy  float64 `yaml:"entropy,omitempty"`
+	Checksum string  `yaml:"checksum,omitempty"`
+}
+
+func (p *RegexPattern) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*p = RegexPattern{Pattern: node.Value}
+		return nil
+	}
+	type plain RegexPattern
+	return node.Decode((*plain)(p))
+}
+
+func (p RegexPattern) MarshalYAML() (interface{}, error) {
+	if p.ID == "" && p.Description == "" && p.Severity == "" && p.MsgSolution == "" && p.Entropy == 0 && p.Checksum == "" {
+		return p.Pattern, nil
+	}
+	type plain RegexPattern
+	return plain(p), nil
+}
+
+// SecretsConfig tunes the candidate filtering of secrets policies
+type SecretsConfig struct {
+	Entropy   float64         `yaml:"entropy,omitempty"`
+	Detectors []string        `yaml:"detectors,omitempty"`
+	Allowlist SecretAllowlist `yaml:"allowlist,omitempty"`
+}
+
+type SecretAllowlist struct {
+	Paths    []string `yaml:"paths,omitempty"`
+	Patterns []string `yaml:"patterns,omitempty"`
+}
+
+// DependenciesConfig holds the rules of dependencies policies, evaluated on the packages of manifests and lockfiles
+type DependenciesConfig struct {
+	Ecosystems  []string           `yaml:"ecosystems,omitempty"`
+	DirectOnly  bool               `yaml:"direct_only,omitempty"`
+	Deny        []DependencyRule   `yaml:"deny,omitempty"`
+	Constraints []DependencyRule   `yaml:"constraints,omitempty"`
+	Licenses    DependencyLicenses `yaml:"licenses,omitempty"`
+	Advisories  string             `yaml:"advisories,omitempty"`
+}
+
+// DependencyRule selects packages by name (* wildcards) and optional ecosystem,
+// version is a constraint like ">=1.2.0 <2" or "<4.17.21 || =5.0.0"
+type DependencyRule struct {
+	ID          string `yaml:"id,omitempty"`
+	Ecosystem   string `yaml:"ecosystem,omitempty"`
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Severity    string `yaml:"severity,omitempty"`
+	MsgSolution string `yaml:"msg_solution,omitempty"`
+}
+
+type DependencyLicenses struct {
+	Allow    []string `yaml:"allow,omitempty"`
+	Deny     []string `yaml:"deny,omitempty"`
+	Unknown  string   `yaml:"unknown,omitempty"`
+	Severity string   `yaml:"severity,omitempty"`
+}
+
+// LicenseConfig classifies the licenses detected by license policies, SPDX identifiers accept * wildcards
+type LicenseConfig struct {
+	Allow       []string `yaml:"allow,omitempty"`
+	Deny        []string `yaml:"deny,omitempty"`
+	Review      []string `yaml:"review,omitempty"`
+	Unknown     string   `yaml:"unknown,omitempty"`
+	HeaderLines int      `yaml:"header_lines,omitempty"`
+}
+
 type Schema struct {
-	Structure string `yaml:"structure"`
-	Strict    bool   `yaml:"strict"`
-	Patch     bool   `yaml:"patch"`
+	Structure string           `yaml:"structure"`
+	Strict    bool             `yaml:"strict"`
+	Patch     bool             `yaml:"patch"`
+	Documents DocumentSelector `yaml:"documents,omitempty"`
+}
+
+// DocumentSelector picks the documents of multi-document YAML files (yml), values are path.Match patterns
+type DocumentSelector struct {
+	APIVersion []string `yaml:"api_version,omitempty"`
+	Kind       []string `yaml:"kind,omitempty"`
 }
 
 type Rego struct {
@@ -131,17 +240,13 @@ const (
 	RemoteURL
 )
 
+// LoadPolicyFile loads a policy file together with the files it includes
 func LoadPolicyFile(filename string) (*PolicyFile, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
+	return LoadPolicySources([]string{filename}, "")
+}
 
-	var policyFile PolicyFile
-	err = yaml.Unmarshal(data, &policyFile)
-	if err != nil {
-		return nil, err
-	}
+// finalizePolicyFile normalizes policy IDs and builds the SARIF rules of a merged policy file
+func finalizePolicyFile(policyFile *PolicyFile) {
 
 	// log.Debug().Interface("raw config", policyFile.Config).Msg("Raw Config data")
 
@@ -177,58 +282,13 @@ func LoadPolicyFile(filename string) (*PolicyFile, error) {
 
 	// Add rules to policyFile
 	policyFile.SARIFRules = rules
-
-	return &policyFile, nil
 }
 
 // Load Remote
 
-// LoadRemotePolicy loads a policy file from a remote HTTPS endpoint
+// LoadRemotePolicy loads a policy file from a remote HTTPS endpoint, relative includes resolve against its URL
 func LoadRemotePolicy(url string, expectedChecksum string) (*PolicyFile, error) {
-	// Create a temporary directory to store the downloaded file
-	remoteDir := filepath.Join(outputDir, "_remote")
-	err := os.MkdirAll(remoteDir, 0755)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
-	}
-	defer os.RemoveAll(remoteDir) // Clean up the temporary directory when done
-
-	// Generate a temporary file name
-	tempFile := filepath.Join(remoteDir, "remote_policy.yaml")
-
-	// Create a resty client
-	client := resty.New()
-
-	// Download the file
-	resp, err := client.R().SetOutput(tempFile).Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download policy file: %w", err)
-	}
-
-	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("failed to download policy file: HTTP status %d", resp.StatusCode())
-	}
-
-	// If a checksum is provided, validate it
-	if expectedChecksum != "" {
-		actualChecksum, err := calculateSHA256(tempFile)
-		if err != nil {
-			log.Fatal().Err(err).Msg("failed to calculate policy checksum")
-		}
-
-		if actualChecksum != expectedChecksum {
-			log.Fatal().Msgf("Policy checksum mismatch: expected %s, got %s", expectedChecksum, actualChecksum)
-
-		}
-	}
-
-	// Load the policy file
-	policyFile, err := LoadPolicyFile(tempFile)
-	if err != nil {
-		log.Fatal().Err(err).Msg("failed to load policy file")
-	}
-
-	return policyFile, nil
+	return LoadPolicySources([]string{url}, expectedChecksum)
 }
 
 func DeterminePolicySource(input string) (PolicySourceType, string, error) {
@@ -0,0 +1,535 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// policySchemaID is the $id of the generated JSON Schema
+const policySchemaID = "https://intercept.cc/schema/policy.schema.json"
+
+var (
+	policySchemaFormat string
+	policySchemaOutput string
+)
+
+var policySchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Generate the JSON Schema or CUE definition of the policy file format",
+	Long: `Generate the JSON Schema (editor autocompletion and validation) or the CUE definition of the policy file format,
+both are derived from the Go types and include the sections required by each policy type.
+
+Examples:
+  $ intercept policy schema > intercept.schema.json
+  $ intercept policy schema --format cue --file intercept.cue`,
+	Run: runPolicySchema,
+}
+
+func init() {
+	policyCmd.AddCommand(policySchemaCmd)
+	policySchemaCmd.Flags().StringVar(&policySchemaFormat, "format", "json", "Schema format : json,cue")
+	policySchemaCmd.Flags().StringVar(&policySchemaOutput, "file", "", "Write the schema to <FILEPATH> instead of stdout")
+}
+
+func runPolicySchema(cmd *cobra.Command, args []string) {
+	var content string
+	switch strings.ToLower(policySchemaFormat) {
+	case "json":
+		data, err := GeneratePolicyJSONSchema()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error generating JSON Schema")
+		}
+		content = string(data) + "\n"
+	case "cue":
+		content = GeneratePolicyCUE()
+	default:
+		log.Fatal().Str("format", policySchemaFormat).Msg("Unsupported schema format (expected json or cue)")
+	}
+
+	if policySchemaOutput == "" {
+		fmt.Print(content)
+		return
+	}
+	if err := os.WriteFile(policySchemaOutput, []byte(content), 0644); err != nil {
+		log.Fatal().Err(err).Str("file", policySchemaOutput).Msg("Error writing schema")
+	}
+	log.Log().Msgf("Policy schema written to %s", policySchemaOutput)
+}
+
+// schemaField describes a yaml field of the policy file format, shared by the JSON Schema and CUE generators
+type schemaField struct {
+	Name     string
+	Required bool
+	Node     schemaNode
+}
+
+type schemaNode struct {
+	Kind        string // string, boolean, integer, number, array, map, object, ref
+	Ref         string
+	Items       *schemaNode
+	Fields      []schemaField
+	Enum        []string
+	Description string
+	OrString    bool
+}
+
+// policySchemaRequired lists the mandatory yaml fields of each named type
+var policySchemaRequired = map[string][]string{
+	"Policy":         {"id", "type"},
+	"HookConfig":     {"name", "endpoint"},
+	"Exception":      {"path", "justification"},
+	"PolicyOverride": {"id"},
+	"RegexPattern":   {"pattern"},
+}
+
+// policySchemaDescriptions documents yaml fields, keyed by "<Type>.<field>"
+var policySchemaDescriptions = map[string]string{
+	"PolicyFile.Include":             "Policy files, directories, globs or URLs loaded before this file, relative to it",
+	"PolicyFile.Overrides":           "Changes applied to policies inherited from includes or earlier --policy sources",
+	"PolicyOverride.disabled":        "Remove the inherited policy from the run",
+	"PolicyOverride.enforcement":     "Replace the enforcement rules of the inherited policy",
+	"Policy.id":                      "Unique policy identifier, normalized to uppercase with dashes",
+	"Policy.type":                    "Policy type, selects the required section (_regex, _schema, _rego, _api, _runtime, _secrets, _dependencies or _license)",
+	"Policy.schedule":                "Cron expression used by observe, mutually exclusive with observe",
+	"Policy.filepattern":             "Regex selecting the target files of the policy",
+	"Policy.include":                 "Doublestar globs selecting target files relative to the target, combined with filepattern",
+	"Policy.exclude":                 "Doublestar globs removing target files selected by filepattern or include",
+	"Policy.max_file_size":           "Skip target files larger than this size (bytes or KB, MB, GB)",
+	"Policy.max_depth":               "Skip target files nested deeper than this number of path segments below the target (1 is the target root)",
+	"Policy.skip_binary":             "Skip target files detected as binary (NUL byte in the first 8KB)",
+	"Policy.ignore":                  "Gitignore patterns excluding target files from this policy, relative to the target",
+	"Policy.observe":                 "Path watched by observe",
+	"Policy.redact":                  "Redaction of matched content in SARIF, logs, webhooks and _debug output, defaults to Flags.redact",
+	"Flags.ignore":                   "Gitignore patterns excluding target files from all policies, on top of the .gitignore and .interceptignore files",
+	"Flags.redact":                   "Default redaction mode of all policies",
+	"Flags.cache_dir":                "Result cache directory, enables the result cache of unchanged files",
+	"Policy.format":                  "Parser of the target files (json, yml, toml, ini, rego), overrides the format of the policy type",
+	"Policy.context_lines":           "Lines before and after each match reported in the SARIF contextRegion (scan, secrets)",
+	"Policy._regex":                  "PCRE2 patterns (scan, assure, api) or RE2 candidates (secrets), plain strings or objects with id, description, severity and msg_solution",
+	"RegexPattern.id":                "Pattern identifier reported in the pattern-id result property",
+	"RegexPattern.severity":          "SARIF level of the results of this pattern, overrides the enforcement level",
+	"RegexPattern.entropy":           "Minimum Shannon entropy (bits per character) of the candidate (secrets)",
+	"RegexPattern.checksum":          "Checksum the candidate must satisfy, verified results (secrets)",
+	"Policy._secrets":                "Built-in detectors, entropy threshold and allowlist (secrets)",
+	"SecretsConfig.entropy":          "Default minimum Shannon entropy of candidates, 3.5 when unset",
+	"SecretsConfig.detectors":        "Built-in detectors, all of them when neither detectors nor _regex are set",
+	"SecretAllowlist.paths":          "Path globs never reported, e.g. test fixtures",
+	"SecretAllowlist.patterns":       "Regexes matched against the candidate, known placeholders are not reported",
+	"Policy._dependencies":           "Deny lists, version constraints, license lists and local advisories applied to manifests and lockfiles (dependencies)",
+	"DependenciesConfig.ecosystems":  "Ecosystems checked, all of them when unset",
+	"DependenciesConfig.direct_only": "Only check direct dependencies",
+	"DependenciesConfig.advisories":  "Local advisory file (YAML or JSON list of id, ecosystem, name, affected, fixed, severity, summary)",
+	"DependencyRule.name":            "Package name, * matches any characters (maven names are groupId:artifactId)",
+	"DependencyRule.version":         "Version constraint, comparators joined by spaces or commas, alternatives by ||",
+	"DependencyRule.severity":        "SARIF level of the results of this rule, overrides the enforcement level",
+	"DependencyLicenses.allow":       "SPDX identifiers allowed, other known licenses are reported",
+	"DependencyLicenses.deny":        "SPDX identifiers reported",
+	"DependencyLicenses.unknown":     "Status of packages without license data, allow when unset",
+	"Policy._license":                "Allow, deny and review lists of SPDX identifiers (license)",
+	"LicenseConfig.unknown":          "Status of licenses in no list, review when unset",
+	"LicenseConfig.header_lines":     "Leading lines of source files searched for SPDX identifiers and license texts, 30 when unset",
+	"Policy._schema":                 "CUE schema (json, yml, toml, ini, api, dockerfile, hcl)",
+	"Policy._rego":                   "Rego module, query and data (rego, dockerfile, hcl)",
+	"Policy._api":                    "API endpoint to audit (api)",
+	"Policy._runtime":                "Goss runtime checks (runtime)",
+	"Policy.exceptions":              "Per-finding exceptions reported as SARIF suppressions",
+	"Schema.structure":               "CUE structure the target content must satisfy",
+	"Schema.documents":               "Documents of multi-document YAML files validated one by one (yml)",
+	"DocumentSelector.api_version":   "apiVersion patterns of the validated documents",
+	"DocumentSelector.kind":          "kind patterns of the validated documents",
+	"Rego.policy_query":              "Rego query, its package must match the module package",
+	"HookConfig.endpoint":            "Webhook URL",
+}
+
+// policySchemaEnums restricts yaml fields to known values, keyed by "<Type>.<field>"
+func policySchemaEnums() map[string][]string {
+	return map[string][]string{
+		"Policy.type":                   sortedPolicyTypes(),
+		"Enforcement.confidence":        lintConfidences,
+		"HookConfig.method":             lintHTTPMethods,
+		"HookConfig.event_types":        lintWebhookEventTypes,
+		"APIConfig.method":              lintHTTPMethods,
+		"Flags.fail_on":                 {"error", "warning", failOnNever},
+		"Flags.engine":                  {engineNative, engineRipgrep},
+		"Flags.redact":                  redactModes,
+		"Policy.redact":                 redactModes,
+		"Policy.format":                 sortedFileFormats(),
+		"RegexPattern.severity":         patternSeverities,
+		"RegexPattern.checksum":         {checksumGitHub, checksumLuhn},
+		"SecretsConfig.detectors":       secretDetectorNames(),
+		"DependenciesConfig.ecosystems": dependencyEcosystems,
+		"DependencyRule.ecosystem":      dependencyEcosystems,
+		"DependencyRule.severity":       patternSeverities,
+		"DependencyLicenses.severity":   patternSeverities,
+		"DependencyLicenses.unknown":    licenseUnknownModes,
+		"LicenseConfig.unknown":         licenseUnknownModes,
+	}
+}
+
+// policySchemaDefinitions are the named types emitted as reusable definitions
+var policySchemaDefinitions = []reflect.Type{
+	reflect.TypeOf(Config{}),
+	reflect.TypeOf(HookConfig{}),
+	reflect.TypeOf(Policy{}),
+	reflect.TypeOf(Enforcement{}),
+	reflect.TypeOf(Metadata{}),
+	reflect.TypeOf(Schema{}),
+	reflect.TypeOf(DocumentSelector{}),
+	reflect.TypeOf(Rego{}),
+	reflect.TypeOf(APIConfig{}),
+	reflect.TypeOf(Runtime{}),
+	reflect.TypeOf(Exception{}),
+	reflect.TypeOf(PolicyOverride{}),
+	reflect.TypeOf(RegexPattern{}),
+	reflect.TypeOf(SecretsConfig{}),
+	reflect.TypeOf(SecretAllowlist{}),
+	reflect.TypeOf(DependenciesConfig{}),
+	reflect.TypeOf(DependencyRule{}),
+	reflect.TypeOf(DependencyLicenses{}),
+	reflect.TypeOf(LicenseConfig{}),
+}
+
+// policySchemaScalarTypes are definitions that may also be written as a plain string
+var policySchemaScalarTypes = []reflect.Type{
+	reflect.TypeOf(RegexPattern{}),
+}
+
+func isSchemaDefinition(t reflect.Type) bool {
+	for _, def := range policySchemaDefinitions {
+		if def == t {
+			return true
+		}
+	}
+	return false
+}
+
+// buildSchemaNode walks a Go type through its yaml tags, typeName is used for anonymous structs (Flags, Metadata of Config)
+func buildSchemaNode(t reflect.Type, typeName string, root bool) schemaNode {
+	switch t.Kind() {
+	case reflect.String:
+		return schemaNode{Kind: "string"}
+	case reflect.Bool:
+		return schemaNode{Kind: "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return schemaNode{Kind: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return schemaNode{Kind: "number"}
+	case reflect.Slice:
+		items := buildSchemaNode(t.Elem(), typeName, false)
+		return schemaNode{Kind: "array", Items: &items}
+	case reflect.Map:
+		values := buildSchemaNode(t.Elem(), typeName, false)
+		return schemaNode{Kind: "map", Items: &values}
+	case reflect.Struct:
+		if !root && isSchemaDefinition(t) {
+			orString := false
+			for _, scalar := range policySchemaScalarTypes {
+				orString = orString || scalar == t
+			}
+			return schemaNode{Kind: "ref", Ref: t.Name(), OrString: orString}
+		}
+		if t.Name() != "" {
+			typeName = t.Name()
+		}
+		node := schemaNode{Kind: "object"}
+		enums := policySchemaEnums()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue // unexported
+			}
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			fieldTypeName := typeName
+			if field.Type.Kind() == reflect.Struct && field.Type.Name() == "" {
+				fieldTypeName = field.Name
+			}
+			child := buildSchemaNode(field.Type, fieldTypeName, false)
+			key := typeName + "." + name
+			if enum, ok := enums[key]; ok {
+				if child.Kind == "array" {
+					child.Items.Enum = enum
+				} else {
+					child.Enum = enum
+				}
+			}
+			child.Description = policySchemaDescriptions[key]
+			node.Fields = append(node.Fields, schemaField{
+				Name:     name,
+				Required: containsString(policySchemaRequired[typeName], name),
+				Node:     child,
+			})
+		}
+		return node
+	default:
+		return schemaNode{Kind: "string"}
+	}
+}
+
+// GeneratePolicyJSONSchema returns the JSON Schema (draft-07, the most widely supported by editors) of the policy file format
+func GeneratePolicyJSONSchema() ([]byte, error) {
+	definitions := map[string]interface{}{}
+	for _, def := range policySchemaDefinitions {
+		schema := schemaNodeToJSON(buildSchemaNode(def, def.Name(), true))
+		if def == reflect.TypeOf(Policy{}) {
+			schema["allOf"] = policyTypeJSONConditions()
+		}
+		definitions[def.Name()] = schema
+	}
+
+	schema := schemaNodeToJSON(buildSchemaNode(reflect.TypeOf(PolicyFile{}), "PolicyFile", true))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["$id"] = policySchemaID
+	schema["title"] = "INTERCEPT policy file"
+	schema["definitions"] = definitions
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func schemaNodeToJSON(node schemaNode) map[string]interface{} {
+	schema := map[string]interface{}{}
+	switch node.Kind {
+	case "ref":
+		if node.OrString {
+			schema["oneOf"] = []interface{}{
+				map[string]interface{}{"type": "string"},
+				map[string]interface{}{"$ref": "#/definitions/" + node.Ref},
+			}
+		} else {
+			schema["$ref"] = "#/definitions/" + node.Ref
+		}
+	case "array":
+		schema["type"] = "array"
+		schema["items"] = schemaNodeToJSON(*node.Items)
+	case "map":
+		schema["type"] = "object"
+		schema["additionalProperties"] = schemaNodeToJSON(*node.Items)
+	case "object":
+		schema["type"] = "object"
+		schema["additionalProperties"] = false
+		properties := map[string]interface{}{}
+		var required []string
+		for _, field := range node.Fields {
+			properties[field.Name] = schemaNodeToJSON(field.Node)
+			if field.Required {
+				required = append(required, field.Name)
+			}
+		}
+		schema["properties"] = properties
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	default:
+		schema["type"] = node.Kind
+	}
+	if len(node.Enum) > 0 {
+		schema["enum"] = node.Enum
+	}
+	if node.Description != "" {
+		schema["description"] = node.Description
+	}
+	return schema
+}
+
+// policyTypeJSONConditions turns the per-type requirements shared with policy lint into if/then clauses
+func policyTypeJSONConditions() []interface{} {
+	var conditions []interface{}
+	for _, policyType := range sortedPolicyTypes() {
+		then := policyTypeJSONRequirement(policyTypeRequirements[policyType])
+		if then == nil {
+			continue
+		}
+		conditions = append(conditions, map[string]interface{}{
+			"if": map[string]interface{}{
+				"required":   []string{"type"},
+				"properties": map[string]interface{}{"type": map[string]interface{}{"const": policyType}},
+			},
+			"then": then,
+		})
+	}
+	return conditions
+}
+
+func policyTypeJSONRequirement(requirement policyTypeRequirement) map[string]interface{} {
+	var required []string
+	properties := map[string]interface{}{}
+	section := func(name string, fields ...string) {
+		required = append(required, name)
+		if len(fields) > 0 {
+			properties[name] = map[string]interface{}{"required": fields}
+		}
+	}
+
+	if requirement.regex {
+		required = append(required, "_regex")
+		properties["_regex"] = map[string]interface{}{"minItems": 1}
+	}
+	if requirement.schema {
+		section("_schema", "structure")
+	}
+	if requirement.rego {
+		section("_rego", "policy_file", "policy_query", "policy_data")
+	}
+	if requirement.api {
+		section("_api", "endpoint")
+	}
+	if requirement.runtime {
+		section("_runtime", "config")
+	}
+
+	then := map[string]interface{}{}
+	if len(required) > 0 {
+		then["required"] = required
+	}
+	if len(properties) > 0 {
+		then["properties"] = properties
+	}
+	if requirement.schemaOrRego {
+		then["anyOf"] = []interface{}{
+			map[string]interface{}{"required": []string{"_schema"}, "properties": map[string]interface{}{"_schema": map[string]interface{}{"required": []string{"structure"}}}},
+			map[string]interface{}{"required": []string{"_rego"}, "properties": map[string]interface{}{"_rego": map[string]interface{}{"required": []string{"policy_file", "policy_query"}}}},
+		}
+	}
+	if requirement.regexOrSchema {
+		then["anyOf"] = []interface{}{
+			map[string]interface{}{"required": []string{"_regex"}, "properties": map[string]interface{}{"_regex": map[string]interface{}{"minItems": 1}}},
+			map[string]interface{}{"required": []string{"_schema"}, "properties": map[string]interface{}{"_schema": map[string]interface{}{"required": []string{"structure"}}}},
+		}
+	}
+	if len(then) == 0 {
+		return nil
+	}
+	return then
+}
+
+// GeneratePolicyCUE returns the CUE definition of the policy file format, #PolicyFile is the entry point
+func GeneratePolicyCUE() string {
+	var b strings.Builder
+	b.WriteString("// INTERCEPT policy file format, generated by intercept policy schema --format cue\n")
+	b.WriteString("package intercept\n\n")
+
+	b.WriteString("#PolicyFile: ")
+	writeCUENode(&b, buildSchemaNode(reflect.TypeOf(PolicyFile{}), "PolicyFile", true), 0, nil)
+	b.WriteString("\n")
+
+	for _, def := range policySchemaDefinitions {
+		b.WriteString("\n#" + def.Name() + ": ")
+		var extra []string
+		if def == reflect.TypeOf(Policy{}) {
+			extra = policyTypeCUEConditions()
+		}
+		writeCUENode(&b, buildSchemaNode(def, def.Name(), true), 0, extra)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func writeCUENode(b *strings.Builder, node schemaNode, depth int, extra []string) {
+	indent := strings.Repeat("\t", depth)
+	switch node.Kind {
+	case "ref":
+		if node.OrString {
+			b.WriteString("string | ")
+		}
+		b.WriteString("#" + node.Ref)
+	case "array":
+		b.WriteString("[...")
+		writeCUENode(b, *node.Items, depth, nil)
+		b.WriteString("]")
+	case "map":
+		b.WriteString("{[string]: ")
+		writeCUENode(b, *node.Items, depth, nil)
+		b.WriteString("}")
+	case "object":
+		b.WriteString("{\n")
+		for _, field := range node.Fields {
+			if field.Node.Description != "" {
+				fmt.Fprintf(b, "%s\t// %s\n", indent, field.Node.Description)
+			}
+			marker := "?"
+			if field.Required {
+				marker = ""
+			}
+			fmt.Fprintf(b, "%s\t%s%s: ", indent, cueLabel(field.Name), marker)
+			writeCUENode(b, field.Node, depth+1, nil)
+			b.WriteString("\n")
+		}
+		for _, line := range extra {
+			fmt.Fprintf(b, "%s\t%s\n", indent, line)
+		}
+		b.WriteString(indent + "}")
+	case "boolean":
+		b.WriteString("bool")
+	case "integer":
+		b.WriteString("int")
+	case "number":
+		b.WriteString("number")
+	default:
+		if len(node.Enum) > 0 {
+			quoted := make([]string, len(node.Enum))
+			for i, value := range node.Enum {
+				quoted[i] = fmt.Sprintf("%q", value)
+			}
+			b.WriteString(strings.Join(quoted, " | "))
+			return
+		}
+		b.WriteString("string")
+	}
+}
+
+// policyTypeCUEConditions renders the per-type requirements as CUE comprehensions
+func policyTypeCUEConditions() []string {
+	var lines []string
+	for _, policyType := range sortedPolicyTypes() {
+		requirement := policyTypeRequirements[policyType]
+		var fields []string
+		if requirement.regex {
+			fields = append(fields, `"_regex": [string | #RegexPattern, ...string | #RegexPattern]`)
+		}
+		if requirement.schema {
+			fields = append(fields, `"_schema": structure: string & !=""`)
+		}
+		if requirement.rego {
+			fields = append(fields, `"_rego": {policy_file: string & !="", policy_query: string & !="", policy_data: string & !=""}`)
+		}
+		if requirement.api {
+			fields = append(fields, `"_api": endpoint: string & !=""`)
+		}
+		if requirement.runtime {
+			fields = append(fields, `"_runtime": config: string & !=""`)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("if type == %q {", policyType))
+		for _, field := range fields {
+			lines = append(lines, "\t"+field)
+		}
+		lines = append(lines, "}")
+	}
+	return lines
+}
+
+// cueLabel quotes labels that are not plain CUE identifiers, "_" prefixed names would be hidden fields otherwise
+func cueLabel(name string) string {
+	if strings.HasPrefix(name, "_") || strings.HasPrefix(name, "#") || strings.ContainsAny(name, "-. ") {
+		return fmt.Sprintf("%q", name)
+	}
+	return name
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,493 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// PolicyTest is a unit test declared on a policy, run against a fixture file or inline content
+type PolicyTest struct {
+	Name     string           `yaml:"name"`
+	Fixture  string           `yaml:"fixture,omitempty"`
+	Content  string           `yaml:"content,omitempty"`
+	Filename string           `yaml:"filename,omitempty"`
+	Expect   PolicyTestExpect `yaml:"expect"`
+}
+
+// PolicyTestExpect holds the expected outcome of a policy test, unset fields are not checked
+type PolicyTestExpect struct {
+	Compliant *bool    `yaml:"compliant,omitempty"`
+	Matches   *int     `yaml:"matches,omitempty"`
+	Messages  []string `yaml:"messages,omitempty"`
+	Absent    []string `yaml:"absent,omitempty"`
+}
+
+// Policy test outcomes
+const (
+	PolicyTestPass  = "PASS"
+	PolicyTestFail  = "FAIL"
+	PolicyTestError = "ERROR"
+	PolicyTestSkip  = "SKIP"
+)
+
+// PolicyTestResult is the outcome of a single policy test
+type PolicyTestResult struct {
+	PolicyFile string
+	PolicyID   string
+	Name       string
+	Status     string
+	Diff       []string
+	Duration   time.Duration
+}
+
+var (
+	policyTestJUnit  string
+	policyTestEngine string
+)
+
+var policyTestCmd = &cobra.Command{
+	Use:   "test [FILE...]",
+	Short: "Run the tests declared on policies against their fixtures",
+	Long: `Run the tests declared on policies (tests: fixture files or inline content with expected outcomes)
+through the same processing as audit, print a pass/fail table with diffs and optionally write a JUnit report.
+
+Examples:
+  $ intercept policy test policies/scan.yaml
+  $ intercept policy test -p policies/config.yaml --junit policy-tests.xml`,
+	Run: runPolicyTest,
+}
+
+func init() {
+	policyCmd.AddCommand(policyTestCmd)
+	policyTestCmd.Flags().StringArrayVarP(&policyFiles, "policy", "p", nil, "Policy <FILEPATH> or <DIRECTORY> to test")
+	policyTestCmd.Flags().StringVar(&policyTestJUnit, "junit", "", "Write a JUnit XML report to <FILEPATH>")
+	policyTestCmd.Flags().StringVarP(&environment, "environment", "e", "", "Environment used to select enforcement levels")
+	policyTestCmd.Flags().StringVar(&policyTestEngine, "engine", "", "Regex engine for scan and assure policies : native,ripgrep (default ripgrep)")
+}
+
+func runPolicyTest(cmd *cobra.Command, args []string) {
+	files, err := expandPolicyPaths(append(args, policyFiles...))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error listing policy files")
+	}
+	if len(files) == 0 {
+		log.Fatal().Msg("At least one policy file is required")
+	}
+
+	// tests write their SARIF reports to a scratch output directory, never to the compliance log
+	workDir, err := os.MkdirTemp("", "intercept_policy_test")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create test directory")
+	}
+	defer os.RemoveAll(workDir)
+	outputDir = workDir
+	outputTypeMatrixConfig.LOG = false
+
+	var results []PolicyTestResult
+	for _, file := range files {
+		fileResults, err := RunPolicyTests(file, workDir)
+		if err != nil {
+			log.Fatal().Err(err).Str("file", file).Msg("Error running policy tests")
+		}
+		results = append(results, fileResults...)
+	}
+
+	failed := printPolicyTestResults(results)
+
+	if policyTestJUnit != "" {
+		if err := writePolicyTestJUnit(policyTestJUnit, results); err != nil {
+			log.Fatal().Err(err).Str("file", policyTestJUnit).Msg("Error writing JUnit report")
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// RunPolicyTests runs every test declared in a policy file, fixtures are resolved relative to the policy file
+func RunPolicyTests(filename, workDir string) ([]PolicyTestResult, error) {
+	loaded, err := LoadPolicyFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	policyData = loaded
+	if err := LoadExceptions(policyData, ""); err != nil {
+		return nil, err
+	}
+	// each policy file may select its own engine unless --engine is set
+	scanEngine = policyTestEngine
+	if err := resolveScanEngine(policyData.Config); err != nil {
+		return nil, err
+	}
+
+	var results []PolicyTestResult
+	for _, policy := range policyData.Policies {
+		for i, test := range policy.Tests {
+			if test.Name == "" {
+				test.Name = fmt.Sprintf("test #%d", i+1)
+			}
+			start := time.Now()
+			result := runPolicyTestCase(policy, test, filepath.Dir(filename), filepath.Join(workDir, fmt.Sprintf("%s-%d", NormalizeFilename(policy.ID), i)))
+			result.PolicyFile = filename
+			result.PolicyID = policy.ID
+			result.Name = test.Name
+			result.Duration = time.Since(start)
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func runPolicyTestCase(policy Policy, test PolicyTest, policyDir, caseDir string) PolicyTestResult {
+	fail := func(status string, format string, args ...interface{}) PolicyTestResult {
+		return PolicyTestResult{Status: status, Diff: []string{fmt.Sprintf(format, args...)}}
+	}
+
+	if policy.Type == "runtime" {
+		return fail(PolicyTestSkip, "runtime policies check the live host and cannot run against fixtures")
+	}
+	if (test.Fixture == "") == (test.Content == "") {
+		return fail(PolicyTestError, "exactly one of fixture or content is required")
+	}
+
+	if err := os.MkdirAll(caseDir, 0755); err != nil {
+		return fail(PolicyTestError, "%v", err)
+	}
+	if err := cleanupOutputDirectories(); err != nil {
+		return fail(PolicyTestError, "%v", err)
+	}
+	if err := createOutputDirectories(false); err != nil {
+		return fail(PolicyTestError, "%v", err)
+	}
+
+	fixture := test.Fixture
+	if fixture != "" && !filepath.IsAbs(fixture) {
+		fixture = filepath.Join(policyDir, fixture)
+	}
+	if test.Content != "" {
+		name := test.Filename
+		if name == "" {
+			name = policyTestFixtureName(policy)
+		}
+		fixture = filepath.Join(caseDir, filepath.Base(name))
+		if err := os.WriteFile(fixture, []byte(test.Content), 0644); err != nil {
+			return fail(PolicyTestError, "%v", err)
+		}
+	}
+
+	// the policy RunID would redirect the SARIF file name, tests always use the policy ID
+	policy.RunID = ""
+	policy.explicitFiles = true
+	targetDir = filepath.Dir(fixture)
+
+	var runErr error
+	if policy.Type == "api" {
+		content, err := os.ReadFile(fixture)
+		if err != nil {
+			return fail(PolicyTestError, "%v", err)
+		}
+		// fixtures stand for the API response body
+		if policy.Schema.Structure != "" {
+			runErr = processWithCUE(policy, content, false)
+		} else {
+			runErr = processWithRegex(policy, content, rgPath, false)
+		}
+	} else {
+		if _, err := os.Stat(fixture); err != nil {
+			return fail(PolicyTestError, "fixture not found: %v", err)
+		}
+		runErr = runPolicyByType(policy, rgPath, gossPath, targetDir, []string{fixture})
+	}
+
+	report, found, err := readPolicyTestReport()
+	if err != nil {
+		return fail(PolicyTestError, "%v", err)
+	}
+	if !found {
+		if runErr != nil {
+			return fail(PolicyTestError, "policy did not produce a report: %v", runErr)
+		}
+		return fail(PolicyTestError, "policy did not produce a report")
+	}
+	if runErr != nil {
+		log.Debug().Err(runErr).Str("policy", policy.ID).Msg("Policy returned an error, checking its report")
+	}
+
+	diff := comparePolicyTestExpect(test.Expect, report)
+	if len(diff) > 0 {
+		return PolicyTestResult{Status: PolicyTestFail, Diff: diff}
+	}
+	return PolicyTestResult{Status: PolicyTestPass}
+}
+
+// policyTestFixtureName names inline content so that parsers and file selections see the right file
+// type, policy types selecting files by name get a file they pick
+func policyTestFixtureName(policy Policy) string {
+	switch format := policyFormat(policy, ""); format {
+	case "nginx":
+		return "nginx.conf"
+	case "apache":
+		return "httpd.conf"
+	case "":
+	default:
+		return "fixture." + format
+	}
+
+	switch policy.Type {
+	case "json", "api", "rego":
+		return "fixture.json"
+	case "yml":
+		return "fixture.yaml"
+	case "toml":
+		return "fixture.toml"
+	case "ini":
+		return "fixture.ini"
+	case "dependencies":
+		return policyTestManifestName(policy.Dependencies.Ecosystems)
+	case "license":
+		return "LICENSE"
+	case "dockerfile":
+		return "Dockerfile"
+	case "hcl":
+		return "main.tf"
+	default:
+		return "fixture.txt"
+	}
+}
+
+// policyTestManifestName is the manifest of the first ecosystem checked by a dependencies policy
+func policyTestManifestName(ecosystems []string) string {
+	ecosystem := ecosystemNPM
+	if len(ecosystems) > 0 {
+		ecosystem = strings.ToLower(ecosystems[0])
+	}
+	switch ecosystem {
+	case ecosystemGo:
+		return "go.mod"
+	case ecosystemMaven:
+		return "pom.xml"
+	case ecosystemPyPI:
+		return "requirements.txt"
+	default:
+		return "package-lock.json"
+	}
+}
+
+// readPolicyTestReport merges the SARIF files written by a single policy run
+func readPolicyTestReport() (SARIFReport, bool, error) {
+	files, err := filepath.Glob(filepath.Join(outputDir, "_sarif", "*.sarif"))
+	if err != nil || len(files) == 0 {
+		return SARIFReport{}, false, err
+	}
+
+	var merged SARIFReport
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return SARIFReport{}, false, err
+		}
+		var report SARIFReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return SARIFReport{}, false, fmt.Errorf("invalid SARIF report %s: %w", file, err)
+		}
+		if len(merged.Runs) == 0 {
+			merged = report
+			continue
+		}
+		for _, run := range report.Runs {
+			merged.Runs[0].Results = append(merged.Runs[0].Results, run.Results...)
+		}
+	}
+	return merged, len(merged.Runs) > 0, nil
+}
+
+// comparePolicyTestExpect returns one diff line per unmet expectation, "-" expected and "+" actual
+func comparePolicyTestExpect(expect PolicyTestExpect, report SARIFReport) []string {
+	var diff []string
+	var messages []string
+	matches := 0
+	for _, result := range report.Runs[0].Results {
+		if result.Level == SARIFError || result.Level == SARIFWarning {
+			messages = append(messages, result.Message.Text)
+		}
+		for _, location := range result.Locations {
+			if snippet := location.PhysicalLocation.Region.Snippet.Text; snippet != "" && snippet != "N/A" {
+				matches++
+			}
+		}
+	}
+
+	if expect.Compliant != nil {
+		if compliant := ComplianceStatus(report); compliant != *expect.Compliant {
+			diff = append(diff, fmt.Sprintf("- compliant: %t", *expect.Compliant), fmt.Sprintf("+ compliant: %t", compliant))
+		}
+	}
+	if expect.Matches != nil && matches != *expect.Matches {
+		diff = append(diff, fmt.Sprintf("- matches: %d", *expect.Matches), fmt.Sprintf("+ matches: %d", matches))
+	}
+
+	missing := false
+	for _, expected := range expect.Messages {
+		found := false
+		for _, message := range messages {
+			if strings.Contains(message, expected) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = true
+			diff = append(diff, fmt.Sprintf("- message: %s", expected))
+		}
+	}
+	if missing {
+		for _, message := range messages {
+			diff = append(diff, fmt.Sprintf("+ message: %s", message))
+		}
+	}
+
+	// absent content (redacted secrets) must not appear anywhere in the report, context regions included
+	if len(expect.Absent) > 0 {
+		var data strings.Builder
+		encoder := json.NewEncoder(&data)
+		encoder.SetEscapeHTML(false)
+		_ = encoder.Encode(report)
+		for _, absent := range expect.Absent {
+			if strings.Contains(data.String(), absent) {
+				diff = append(diff, fmt.Sprintf("+ absent: %s", absent))
+			}
+		}
+	}
+
+	return diff
+}
+
+// printPolicyTestResults prints the pass/fail table with the diff of failed tests and returns the number of failures
+func printPolicyTestResults(results []PolicyTestResult) int {
+	policyWidth, nameWidth := len("POLICY"), len("TEST")
+	for _, result := range results {
+		policyWidth = max(policyWidth, len(result.PolicyID))
+		nameWidth = max(nameWidth, len(result.Name))
+	}
+	row := fmt.Sprintf("%%-%ds  %%-%ds  %%-6s  %%s\n", policyWidth, nameWidth)
+
+	fmt.Printf(row, "POLICY", "TEST", "RESULT", "TIME")
+
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status]++
+		fmt.Printf(row, result.PolicyID, result.Name, result.Status, fmt.Sprintf("%dms", result.Duration.Milliseconds()))
+		if result.Status != PolicyTestPass {
+			for _, line := range result.Diff {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d test(s): %d passed, %d failed, %d error(s), %d skipped\n",
+		len(results), counts[PolicyTestPass], counts[PolicyTestFail], counts[PolicyTestError], counts[PolicyTestSkip])
+
+	return counts[PolicyTestFail] + counts[PolicyTestError]
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writePolicyTestJUnit writes the results as a JUnit report, one test suite per policy file
+func writePolicyTestJUnit(filename string, results []PolicyTestResult) error {
+	report := junitTestSuites{}
+	suites := map[string]int{}
+
+	for _, result := range results {
+		index, ok := suites[result.PolicyFile]
+		if !ok {
+			index = len(report.Suites)
+			suites[result.PolicyFile] = index
+			report.Suites = append(report.Suites, junitTestSuite{Name: result.PolicyFile})
+		}
+		suite := &report.Suites[index]
+
+		testCase := junitTestCase{
+			Name:      result.Name,
+			ClassName: result.PolicyID,
+			Time:      fmt.Sprintf("%.3f", result.Duration.Seconds()),
+		}
+		message := &junitMessage{Text: strings.Join(result.Diff, "\n")}
+		if len(result.Diff) > 0 {
+			message.Message = result.Diff[0]
+		}
+
+		switch result.Status {
+		case PolicyTestFail:
+			testCase.Failure = message
+			suite.Failures++
+			report.Failures++
+		case PolicyTestError:
+			testCase.Error = message
+			suite.Errors++
+			report.Errors++
+		case PolicyTestSkip:
+			testCase.Skipped = message
+			suite.Skipped++
+			report.Skipped++
+		}
+
+		suite.Tests++
+		report.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	for i := range report.Suites {
+		var total time.Duration
+		for _, result := range results {
+			if result.PolicyFile == report.Suites[i].Name {
+				total += result.Duration
+			}
+		}
+		report.Suites[i].Time = fmt.Sprintf("%.3f", total.Seconds())
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append([]byte(xml.Header), data...), 0644)
+}
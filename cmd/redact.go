@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	redactFull    = "full"
+	redactPartial = "partial"
+	redactHash    = "hash"
+
+	redactedText = "[REDACTED]"
+)
+
+var redactModes = []string{redactFull, redactPartial, redactHash}
+
+// policyRedaction returns the redaction mode of a policy, Flags.redact is the default of all policies.
+// Unknown modes fall back to full redaction so that a typo never leaks content.
+func policyRedaction(policy Policy) string {
+	mode := policy.Redact
+	if mode == "" {
+		mode = policyData.Config.Flags.Redact
+	}
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode != "" && !containsString(redactModes, mode) {
+		log.Warn().Str("policy", policy.ID).Str("redact", mode).Msg("unknown redaction mode, using full")
+		return redactFull
+	}
+	return mode
+}
+
+// redactText hides matched content : full replaces it, partial keeps a few characters at both ends
+// and hash keeps the SHA256 used by exceptions (snippet_sha256) so findings stay comparable
+func redactText(mode, text string) string {
+	if text == "" || text == "N/A" {
+		return text
+	}
+	switch mode {
+	case redactFull:
+		return redactedText
+	case redactPartial:
+		runes := []rune(text)
+		keep := min(len(runes)/4, 4)
+		return string(runes[:keep]) + "****" + string(runes[len(runes)-keep:])
+	case redactHash:
+		return "sha256:" + hashSnippet(text)
+	}
+	return text
+}
+
+// redactResults applies the policy redaction to the snippets, context regions and messages of its results.
+// It runs after exceptions are applied, fingerprints are computed beforehand so baselines keep matching.
+// Context regions span neighbouring lines, every match of the run is redacted in all of them.
+func redactResults(policy Policy, results []Result) []Result {
+	mode := policyRedaction(policy)
+	if mode == "" {
+		return results
+	}
+
+	var matches []string
+	for _, result := range results {
+		for _, location := range result.Locations {
+			if text := location.PhysicalLocation.Region.Snippet.Text; text != "" && text != "N/A" && !containsString(matches, text) {
+				matches = append(matches, text)
+			}
+		}
+	}
+	// longer matches first so that a match holding another one is replaced whole
+	sort.SliceStable(matches, func(i, j int) bool { return len(matches[i]) > len(matches[j]) })
+
+	for i := range results {
+		if results[i].Fingerprints == nil {
+			results[i].Fingerprints = map[string]string{}
+		}
+		results[i].Fingerprints[fingerprintKey] = fingerprintResult(results[i])
+
+		for j := range results[i].Locations {
+			if context := results[i].Locations[j].PhysicalLocation.ContextRegion; context != nil {
+				for _, match := range matches {
+					context.Snippet.Text = strings.ReplaceAll(context.Snippet.Text, match, redactText(mode, match))
+				}
+			}
+			snippet := &results[i].Locations[j].PhysicalLocation.Region.Snippet
+			if snippet.Text == "" || snippet.Text == "N/A" {
+				continue
+			}
+			redacted := redactText(mode, snippet.Text)
+			results[i].Message.Text = strings.ReplaceAll(results[i].Message.Text, snippet.Text, redacted)
+			snippet.Text = redacted
+		}
+	}
+	return results
+}
+
+// redactDebugOutput is deferred by the ripgrep processors right after creating their _debug output, before
+// the deferred flush and close, so it runs last : the raw matches kept in _debug never outlive the SARIF
+// redaction, whichever way the policy ends
+func redactDebugOutput(policy Policy, filePath string) {
+	if err := redactDebugFile(policy, filePath); err != nil {
+		log.Error().Err(err).Msg("error redacting JSON output file")
+	}
+}
+
+// redactDebugFile rewrites the ripgrep JSON kept in _debug with the matches redacted.
+// Output that was never patched into an array is read line by line, lines that don't parse are dropped.
+func redactDebugFile(policy Policy, filePath string) error {
+	mode := policyRedaction(policy)
+	if mode == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading debug file: %w", err)
+	}
+	var rgOutputs []map[string]interface{}
+	if err := json.Unmarshal(data, &rgOutputs); err != nil {
+		rgOutputs = nil
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			var rgOutput map[string]interface{}
+			if json.Unmarshal(line, &rgOutput) == nil {
+				rgOutputs = append(rgOutputs, rgOutput)
+			}
+		}
+	}
+
+	for _, rgOutput := range rgOutputs {
+		if rgOutput["type"] != "match" {
+			continue
+		}
+		matchData, _ := rgOutput["data"].(map[string]interface{})
+		lines, _ := matchData["lines"].(map[string]interface{})
+		submatches, _ := matchData["submatches"].([]interface{})
+
+		lineText, _ := lines["text"].(string)
+		for _, item := range submatches {
+			submatch, _ := item.(map[string]interface{})
+			match, _ := submatch["match"].(map[string]interface{})
+			text, _ := match["text"].(string)
+			if text == "" {
+				continue
+			}
+			redacted := redactText(mode, text)
+			lineText = strings.ReplaceAll(lineText, text, redacted)
+			match["text"] = redacted
+			// offsets would point into content that is no longer there
+			delete(submatch, "start")
+			delete(submatch, "end")
+		}
+		if lines != nil {
+			lines["text"] = lineText
+		}
+	}
+
+	redactedData, err := json.MarshalIndent(rgOutputs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling debug file: %w", err)
+	}
+	return os.WriteFile(filePath, redactedData, 0644)
+}
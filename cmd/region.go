@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+)
+
+// maxContextLines bounds the context_lines of a policy, larger regions bloat reports without helping reviewers
+const maxContextLines = 50
+
+// matchRegion locates a match within lines, the text ripgrep reports starting at lineNumber and absoluteOffset.
+// start and end are byte offsets of the match in lines, columns are 1-based byte columns and endColumn is exclusive.
+func matchRegion(lines string, lineNumber, absoluteOffset, start, end int) Region {
+	start = min(max(start, 0), len(lines))
+	end = min(max(end, start), len(lines))
+
+	// a match ending with the line break ends on its last line
+	last := end
+	if last > start && lines[last-1] == '\n' {
+		last--
+	}
+
+	byteOffset := absoluteOffset + start
+	return Region{
+		StartLine:   lineNumber + strings.Count(lines[:start], "\n"),
+		StartColumn: start - (strings.LastIndexByte(lines[:start], '\n') + 1) + 1,
+		EndLine:     lineNumber + strings.Count(lines[:last], "\n"),
+		EndColumn:   last - (strings.LastIndexByte(lines[:last], '\n') + 1) + 1,
+		ByteOffset:  &byteOffset,
+		ByteLength:  end - start,
+		Snippet:     Snippet{Text: lines[start:end]},
+	}
+}
+
+// sourceLines caches the lines of the files results point to, to build their context regions
+type sourceLines map[string][]string
+
+func (s sourceLines) get(path string) []string {
+	if lines, ok := s[path]; ok {
+		return lines
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Debug().Err(err).Str("file", path).Msg("no context region, file not readable")
+		s[path] = nil
+		return nil
+	}
+	s[path] = splitSourceLines(string(content))
+	return s[path]
+}
+
+func splitSourceLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// contextRegion returns the lines of path around a region, nil when context is disabled or the file is not readable
+func (s sourceLines) contextRegion(path string, region Region, contextLines int) *Region {
+	if contextLines <= 0 || region.StartLine < 1 {
+		return nil
+	}
+	lines := s.get(path)
+	if len(lines) == 0 {
+		return nil
+	}
+	contextLines = min(contextLines, maxContextLines)
+
+	endLine := max(region.EndLine, region.StartLine)
+	first := max(region.StartLine-contextLines, 1)
+	last := min(endLine+contextLines, len(lines))
+	if first > last {
+		return nil
+	}
+	return &Region{
+		StartLine: first,
+		EndLine:   last,
+		Snippet:   Snippet{Text: strings.Join(lines[first-1:last], "")},
+	}
+}
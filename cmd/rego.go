@@ -54,8 +54,14 @@ func ProcessRegoType(policy Policy, targetDir string, filePaths []string) error
 			return fmt.Errorf("error reading input file %s: %w", filePath, err)
 		}
 
-		var input map[string]interface{}
-		if json.Valid(fileContent) {
+		var input interface{}
+		if policy.Format != "" {
+			// the format field replaces the JSON detection and the blocks fallback
+			if input, err = parseFileFormat(policyFormat(policy, ""), fileContent); err != nil {
+				log.Error().Err(err).Str("file", filePath).Msg("Error parsing input file")
+				return fmt.Errorf("error parsing input file %s: %w", filePath, err)
+			}
+		} else if json.Valid(fileContent) {
 			// If the file is valid JSON, use it directly as input
 			if err := json.Unmarshal(fileContent, &input); err != nil {
 				log.Error().Err(err).Str("file", filePath).Msg("Error parsing input JSON")
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resultCacheVersion changes whenever cached entries can't be read back by a newer build
+const resultCacheVersion = "1"
+
+var (
+	resultCacheEnabled bool
+	resultCacheDir     string
+)
+
+// cacheablePolicyTypes evaluate each file on its own, so their results can be reused per file
+var cacheablePolicyTypes = []string{"scan", "secrets", "json", "yml", "toml", "ini", "rego", "dependencies", "dockerfile", "hcl"}
+
+type resultCacheEntry struct {
+	Version string   `json:"version"`
+	Results []Result `json:"results"`
+}
+
+// resolveResultCache enables the cache from --cache, --cache-dir or Config.Flags.cache_dir
+func resolveResultCache(config Config) error {
+	if resultCacheDir == "" {
+		resultCacheDir = config.Flags.CacheDir
+	}
+	if resultCacheDir != "" {
+		resultCacheEnabled = true
+	}
+	if !resultCacheEnabled {
+		return nil
+	}
+	if resultCacheDir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return fmt.Errorf("no cache directory, use --cache-dir: %w", err)
+		}
+		resultCacheDir = filepath.Join(dir, "intercept", "results")
+	}
+	if err := os.MkdirAll(resultCacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create result cache %s: %w", resultCacheDir, err)
+	}
+	log.Debug().Str("dir", resultCacheDir).Msg("Result cache enabled")
+	return nil
+}
+
+func isCacheablePolicy(policy Policy) bool {
+	return resultCacheEnabled && containsString(cacheablePolicyTypes, policy.Type) && !policy.Schema.Patch
+}
+
+// policyCacheHash covers everything besides the file that changes the results of a policy :
+// its definition and assets, the exceptions, the environment and the default redaction
+func policyCacheHash(policy Policy) string {
+	hash := sha256.New()
+
+	// the run IDs change on every audit
+	policy.RunID = ""
+	policy.InterceptID = ""
+	data, _ := yaml.Marshal(policy)
+	hash.Write(data)
+	for _, asset := range policyAssetPaths(&policy) {
+		if *asset == "" {
+			continue
+		}
+		content, _ := os.ReadFile(*asset)
+		fmt.Fprintf(hash, "|%s|%x", *asset, sha256.Sum256(content))
+	}
+
+	exceptionMutex.RLock()
+	now := time.Now()
+	for _, exception := range exceptionList {
+		fmt.Fprintf(hash, "|%s|%s|%s|%d|%s|%s|%t", exception.PolicyID, exception.ID, exception.Path, exception.Line, exception.SnippetHash, exception.kind, exception.expired(now))
+	}
+	exceptionMutex.RUnlock()
+
+	fmt.Fprintf(hash, "|%s|%s", environment, policyData.Config.Flags.Redact)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// cacheEngineVersion identifies the code producing the results, a new build or engine invalidates the cache
+func cacheEngineVersion(policy Policy) string {
+	version := fmt.Sprintf("%s/%s", resultCacheVersion, buildVersion)
+	switch {
+	case policy.Type == "secrets":
+		version += "/native/" + nativeEngineVersion
+	case policy.Type == "scan" && useNativeEngine():
+		version += "/native/" + nativeEngineVersion
+	case policy.Type == "scan":
+		version += "/" + engineRipgrep
+	}
+	return version
+}
+
+// resultCacheKey keys the results of one policy on one file, the path is part of the key as results carry it
+func resultCacheKey(policyHash, fileHash, engineVersion, uri string) string {
+	hash := sha256.Sum256([]byte(policyHash + "|" + fileHash + "|" + engineVersion + "|" + uri))
+	return hex.EncodeToString(hash[:])
+}
+
+func resultCachePath(key string) string {
+	return filepath.Join(resultCacheDir, key[:2], key+".json")
+}
+
+func readResultCache(key string) ([]Result, bool) {
+	data, err := os.ReadFile(resultCachePath(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry resultCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Version != resultCacheVersion {
+		return nil, false
+	}
+	return entry.Results, true
+}
+
+func writeResultCache(key string, results []Result) error {
+	data, err := json.Marshal(resultCacheEntry{Version: resultCacheVersion, Results: results})
+	if err != nil {
+		return err
+	}
+	path := resultCachePath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// write and rename so parallel runs never read a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// processCachedPolicy reuses the cached results of unchanged files and only evaluates the others,
+// the policy SARIF file then holds both, reused results marked as cached
+func processCachedPolicy(policy Policy, rgPath string, files []FileInfo) {
+	policyHash := policyCacheHash(policy)
+	engineVersion := cacheEngineVersion(policy)
+	wholeTarget := searchesWholeTarget(policy)
+
+	var cached []Result
+	var misses []string
+	missKeys := make(map[string]string)
+
+	for _, file := range files {
+		// scans of the whole target skip hidden files, the file list must match
+		if wholeTarget && (policy.Type == "scan" || policy.Type == "secrets") && isHiddenTargetPath(targetDir, file.Path) {
+			continue
+		}
+		uri := filepath.ToSlash(virtualTargetPath(file.Path))
+		if file.Hash == "" {
+			misses = append(misses, file.Path)
+			continue
+		}
+		key := resultCacheKey(policyHash, file.Hash, engineVersion, uri)
+		if results, ok := readResultCache(key); ok {
+			for i := range results {
+				results[i].Properties.Cached = true
+			}
+			cached = append(cached, results...)
+			continue
+		}
+		misses = append(misses, file.Path)
+		missKeys[uri] = key
+	}
+
+	log.Debug().Str("policy", policy.ID).Int("cached", len(files)-len(misses)).Int("evaluated", len(misses)).Msg("Result cache")
+
+	reportID := policy.ID
+	if policy.RunID != "" {
+		reportID = policy.RunID
+	}
+
+	var fresh []Result
+	if len(misses) > 0 {
+		policy.explicitFiles = true
+		if err := runPolicyByType(policy, rgPath, gossPath, targetDir, misses); err != nil {
+			log.Debug().Msgf("Error processing %s-type policy %s: %v ", policy.Type, policy.ID, err)
+			return
+		}
+		report, err := readSARIFReport(reportID)
+		if err != nil {
+			log.Error().Err(err).Str("policy", policy.ID).Msg("Error reading policy results, not cached")
+			return
+		}
+		if len(report.Runs) > 0 {
+			fresh = report.Runs[0].Results
+		}
+
+		byURI := make(map[string][]Result)
+		for _, result := range fresh {
+			if len(result.Locations) > 0 {
+				uri := result.Locations[0].PhysicalLocation.ArtifactLocation.URI
+				byURI[uri] = append(byURI[uri], result)
+			}
+		}
+		for uri, key := range missKeys {
+			if err := writeResultCache(key, byURI[uri]); err != nil {
+				log.Debug().Err(err).Str("policy", policy.ID).Msg("Error writing result cache")
+			}
+		}
+	}
+
+	if len(cached) == 0 {
+		if len(misses) == 0 {
+			// every file is unchanged and compliant
+			if err := writeSARIFReport(reportID, cachedSARIFReport(policy, []Result{cachedCompliantResult(policy)})); err != nil {
+				log.Error().Err(err).Str("policy", policy.ID).Msg("error writing SARIF report")
+			}
+		}
+		return
+	}
+
+	// per-run summaries (compliant notes) only stand when no file has results
+	var results []Result
+	for _, result := range fresh {
+		if len(result.Locations) > 0 && result.Locations[0].PhysicalLocation.ArtifactLocation.URI != "N/A" {
+			results = append(results, result)
+		}
+	}
+	results = append(results, cached...)
+
+	if outputTypeMatrixConfig.LOG {
+		PostResultsToComplianceLog(cachedSARIFReport(policy, cached))
+	}
+
+	if err := writeSARIFReport(reportID, cachedSARIFReport(policy, results)); err != nil {
+		log.Error().Err(err).Str("policy", policy.ID).Msg("error writing SARIF report")
+	}
+}
+
+func cachedSARIFReport(policy Policy, results []Result) SARIFReport {
+	sarifReport := newPolicySARIFReport(policy)
+	sarifReport.Runs[0].Results = results
+	sarifReport.Runs[0].Invocations[0].Properties.ReportCompliant = ComplianceStatus(sarifReport)
+	return sarifReport
+}
+
+func cachedCompliantResult(policy Policy) Result {
+	result := compliantResult(policy, "No violations found")
+	result.Properties.Cached = true
+	return result
+}
+
+func readSARIFReport(policyID string) (SARIFReport, error) {
+	filename := filepath.Join("_sarif", fmt.Sprintf("%s.sarif", NormalizeFilename(policyID)))
+	if outputDir != "" {
+		filename = filepath.Join(outputDir, filename)
+	}
+	var report SARIFReport
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return report, err
+	}
+	err = json.Unmarshal(data, &report)
+	return report, err
+}
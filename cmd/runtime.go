@@ -125,8 +125,8 @@ func ProcessRuntimeType(policy Policy, gossPath string, targetDir string, filePa
 		if len(sarifReport.Runs) == 0 {
 			log.Warn().Msg("Runtime SARIF contains no runs")
 		} else {
-			// Post merged SARIF report to webhooks
-			if err := PostResultsToWebhooks(sarifReport); err != nil {
+			// Post the policy SARIF report to webhooks, without the findings known in the baseline
+			if err := PostResultsToWebhooks(baselineReport(sarifReport)); err != nil {
 				log.Error().Err(err).Msg("Failed to post Runtime Results to webhooks")
 			}
 		}
@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -105,6 +106,61 @@ func calculateSARIFLevel(policy Policy, environment string) SARIFLevel {
 	)
 }
 
+// newPolicySARIFReport returns the empty single run report that the generator of a policy type fills with its results
+func newPolicySARIFReport(policy Policy) SARIFReport {
+	return SARIFReport{
+		Version: "2.1.0",
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Runs: []Run{
+			{
+				Tool: Tool{
+					Driver: Driver{
+						FullName:        fmt.Sprintf("%s %s", "INTERCEPT", buildVersion),
+						Name:            "INTERCEPT",
+						Version:         smVersion,
+						SemanticVersion: smVersion,
+						InformationURI:  "https://intercept.cc",
+						Rules:           policyData.SARIFRules,
+					},
+				},
+
+				Results:     []Result{},
+				Invocations: []Invocation{{ExecutionSuccessful: true, Properties: InvocationProperties{}}},
+			},
+		},
+	}
+}
+
+// policyResultProperties returns the properties shared by the results of a policy, without a level
+func policyResultProperties(policy Policy) ResultProperties {
+	return ResultProperties{
+		ResultType:      "detail",
+		ObserveRunId:    policy.RunID,
+		ResultTimestamp: time.Now().Format(time.RFC3339),
+		Environment:     environment,
+		Name:            policy.Metadata.Name,
+		Description:     policy.Metadata.Description,
+		MsgError:        policy.Metadata.MsgError,
+		MsgSolution:     policy.Metadata.MsgSolution,
+	}
+}
+
+// compliantResult is the note reported by a policy without violations, text says what was not found
+func compliantResult(policy Policy, text string) Result {
+	properties := policyResultProperties(policy)
+	properties.SarifInt = sarifLevelToInt(SARIFNote)
+	return Result{
+		RuleID:  policy.ID,
+		Level:   SARIFNote,
+		Message: Message{Text: fmt.Sprintf("Policy %s is compliant: %s", policy.ID, text)},
+		Locations: []Location{{PhysicalLocation: PhysicalLocation{
+			ArtifactLocation: ArtifactLocation{URI: "N/A"},
+			Region:           Region{StartLine: 1, StartColumn: 1, EndColumn: 1, Snippet: Snippet{Text: "N/A"}},
+		}}},
+		Properties: properties,
+	}
+}
+
 // RipgrepOutput represents the structure of the ripgrep JSON output
 type RipgrepOutput struct {
 	Type string `json:"type"`
@@ -112,14 +168,18 @@ type RipgrepOutput struct {
 		Path struct {
 			Text string `json:"text"`
 		} `json:"path"`
-		LineNumber int `json:"line_number"`
-		Lines      struct {
+		LineNumber     int `json:"line_number"`
+		AbsoluteOffset int `json:"absolute_offset"`
+		Lines          struct {
 			Text string `json:"text"`
 		} `json:"lines"`
 		Submatches []struct {
 			Match struct {
 				Text string `json:"text"`
 			} `json:"match"`
+			Start   int  `json:"start"`
+			End     int  `json:"end"`
+			Pattern *int `json:"pattern"`
 		} `json:"submatches"`
 	} `json:"data"`
 }
@@ -152,11 +212,27 @@ type Driver struct {
 }
 
 type Result struct {
-	RuleID     string           `json:"ruleId"`
-	Level      SARIFLevel       `json:"level"`
-	Message    Message          `json:"message"`
-	Locations  []Location       `json:"locations,omitempty"`
-	Properties ResultProperties `json:"properties,omitempty"`
+	RuleID        string            `json:"ruleId"`
+	Level         SARIFLevel        `json:"level"`
+	Message       Message           `json:"message"`
+	Locations     []Location        `json:"locations,omitempty"`
+	Suppressions  []Suppression     `json:"suppressions,omitempty"`
+	BaselineState string            `json:"baselineState,omitempty"`
+	Fingerprints  map[string]string `json:"fingerprints,omitempty"`
+	Properties    ResultProperties  `json:"properties,omitempty"`
+}
+
+type Suppression struct {
+	Kind          string                `json:"kind"`
+	Status        string                `json:"status,omitempty"`
+	Justification string                `json:"justification,omitempty"`
+	Properties    SuppressionProperties `json:"properties,omitempty"`
+}
+
+type SuppressionProperties struct {
+	ExceptionID string `json:"exception-id,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	Expires     string `json:"expires,omitempty"`
 }
 
 type Message struct {
@@ -164,12 +240,20 @@ type Message struct {
 }
 
 type Location struct {
-	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+	PhysicalLocation PhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []LogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type LogicalLocation struct {
+	Name               string `json:"name,omitempty"`
+	FullyQualifiedName string `json:"fullyQualifiedName,omitempty"`
+	Kind               string `json:"kind,omitempty"`
 }
 
 type PhysicalLocation struct {
 	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
 	Region           Region           `json:"region,omitempty"`
+	ContextRegion    *Region          `json:"contextRegion,omitempty"`
 }
 
 type ArtifactLocation struct {
@@ -179,7 +263,10 @@ type ArtifactLocation struct {
 type Region struct {
 	StartLine   int     `json:"startLine,omitempty"`
 	StartColumn int     `json:"startColumn,omitempty"`
+	EndLine     int     `json:"endLine,omitempty"`
 	EndColumn   int     `json:"endColumn,omitempty"`
+	ByteOffset  *int    `json:"byteOffset,omitempty"`
+	ByteLength  int     `json:"byteLength,omitempty"`
 	Snippet     Snippet `json:"snippet,omitempty"`
 }
 
@@ -232,20 +319,42 @@ type ResultProperties struct {
 	MsgError        string `json:"msg-error"`
 	MsgSolution     string `json:"msg-solution"`
 	SarifInt        int    `json:"sarif-int"`
+	ExceptionStatus string `json:"exception-status,omitempty"`
+	ExceptionID     string `json:"exception-id,omitempty"`
+	PatternID       string `json:"pattern-id,omitempty"`
+	Verified        *bool  `json:"verified,omitempty"`
+	PackageURL      string `json:"purl,omitempty"`
+	DependencyScope string `json:"dependency-scope,omitempty"`
+	License         string `json:"license,omitempty"`
+	LicenseSource   string `json:"license-source,omitempty"`
+	LicenseStatus   string `json:"license-status,omitempty"`
+	Instruction     string `json:"instruction,omitempty"`
+	Stage           string `json:"stage,omitempty"`
+	ResourceAddress string `json:"resource-address,omitempty"`
+	DocumentIndex   *int   `json:"document-index,omitempty"`
+	Cached          bool   `json:"cached,omitempty"`
 }
 
 type InvocationProperties struct {
-	RunId             string `json:"run-id"`
-	StartTime         string `json:"start-time"`
-	EndTime           string `json:"end-time"`
-	ExecutionTimeInMs string `json:"execution-time-ms"`
-	Environment       string `json:"environment"`
-	Debug             string `json:"debug"`
-	ReportTimestamp   string `json:"report-timestamp"`
-	HostData          string `json:"host-data"`
-	HostFingerprint   string `json:"host-fingerprint"`
-	ReportStatus      string `json:"report-status"`
-	ReportCompliant   bool   `json:"report-compliant"`
+	RunId             string         `json:"run-id"`
+	StartTime         string         `json:"start-time"`
+	EndTime           string         `json:"end-time"`
+	ExecutionTimeInMs string         `json:"execution-time-ms"`
+	Environment       string         `json:"environment"`
+	Debug             string         `json:"debug"`
+	ReportTimestamp   string         `json:"report-timestamp"`
+	HostData          string         `json:"host-data"`
+	HostFingerprint   string         `json:"host-fingerprint"`
+	ReportStatus      string         `json:"report-status"`
+	ReportCompliant   bool           `json:"report-compliant"`
+	SuppressedResults int            `json:"suppressed-results"`
+	ExpiredExceptions int            `json:"expired-exceptions"`
+	NewResults        int            `json:"new-results,omitempty"`
+	UnchangedResults  int            `json:"unchanged-results,omitempty"`
+	AbsentResults     int            `json:"absent-results,omitempty"`
+	CachedResults     int            `json:"cached-results,omitempty"`
+	SelectedFiles     map[string]int `json:"selected-files,omitempty"`
+	LicenseInventory  map[string]int `json:"license-inventory,omitempty"`
 }
 
 type Invocation struct {
@@ -308,7 +417,9 @@ func GenerateSARIFReport(inputFile string, policy Policy) (SARIFReport, error) {
 	}
 
 	var results []Result
-	noMatches := len(rgOutputs) == 1 && rgOutputs[0].Type == "summary"
+	// every pattern search ends with its own summary, the policy complies when they completed without a match
+	noMatches := slices.ContainsFunc(rgOutputs, func(output RipgrepOutput) bool { return output.Type == "summary" }) &&
+		!slices.ContainsFunc(rgOutputs, func(output RipgrepOutput) bool { return output.Type == "match" })
 
 	if policy.Type == "scan" && noMatches {
 		// For SCAN policies with no matches, add a compliance result
@@ -347,6 +458,8 @@ func GenerateSARIFReport(inputFile string, policy Policy) (SARIFReport, error) {
 		})
 	} else {
 		// Process ripgrep output and add results to SARIF report
+		matcher := newPatternMatcher(policy.Regex)
+		sources := sourceLines{}
 		for _, rgOutput := range rgOutputs {
 			if rgOutput.Type == "match" {
 
@@ -354,15 +467,23 @@ func GenerateSARIFReport(inputFile string, policy Policy) (SARIFReport, error) {
 
 				for _, submatch := range rgOutput.Data.Submatches {
 					matchText := submatch.Match.Text
-					startColumn := strings.Index(rgOutput.Data.Lines.Text, matchText) + 1
-					endColumn := startColumn + len(matchText)
+					start, end := submatch.Start, submatch.End
+					if end <= start {
+						// offsets missing from the engine output
+						start = max(strings.Index(rgOutput.Data.Lines.Text, matchText), 0)
+						end = start + len(matchText)
+					}
+					region := matchRegion(rgOutput.Data.Lines.Text, max(rgOutput.Data.LineNumber, 1), rgOutput.Data.AbsoluteOffset, start, end)
+					region.Snippet.Text = matchText
+
+					pattern := matcher.match(submatch.Pattern)
 
 					var message string
 
 					if len(matchText) != 0 {
-						message = fmt.Sprintf("Policy violation: %s Matched text: %s", policy.Metadata.Name, matchText)
+						message = fmt.Sprintf("Policy violation: %s%s Matched text: %s", policy.Metadata.Name, patternLabel(pattern), matchText)
 					} else {
-						message = fmt.Sprintf("Policy violation: %s", policy.Metadata.Name)
+						message = fmt.Sprintf("Policy violation: %s%s", policy.Metadata.Name, patternLabel(pattern))
 					}
 
 					result := Result{
@@ -375,14 +496,8 @@ func GenerateSARIFReport(inputFile string, policy Policy) (SARIFReport, error) {
 							{
 								PhysicalLocation: PhysicalLocation{
 									ArtifactLocation: ArtifactLocation{URI: rgOutput.Data.Path.Text},
-									Region: Region{
-										StartLine:   max(rgOutput.Data.LineNumber, 1),
-										StartColumn: max(startColumn, 1),
-										EndColumn:   max(endColumn, 1),
-										Snippet: Snippet{
-											Text: matchText,
-										},
-									},
+									Region:           region,
+									ContextRegion:    sources.contextRegion(rgOutput.Data.Path.Text, region, policy.ContextLines),
 								},
 							},
 						},
@@ -398,6 +513,7 @@ func GenerateSARIFReport(inputFile string, policy Policy) (SARIFReport, error) {
 							SarifInt:        sarifLevelToInt(sarifLevel),
 						},
 					}
+					applyPatternToResult(&result, pattern)
 
 					results = append(results, result)
 				}
@@ -407,6 +523,8 @@ func GenerateSARIFReport(inputFile string, policy Policy) (SARIFReport, error) {
 
 	sarifReport.Runs[0].Results = results
 
+	sarifReport.Runs[0].Results = applyExceptions(virtualizeResultURIs(sarifReport.Runs[0].Results))
+	sarifReport.Runs[0].Results = redactResults(policy, sarifReport.Runs[0].Results)
 	sarifReport.Runs[0].Invocations[0].Properties.ReportCompliant = ComplianceStatus(sarifReport)
 
 	if outputTypeMatrixConfig.LOG {
@@ -509,9 +627,16 @@ func GenerateAssureSARIFReport(inputFile string, policy Policy, status string) (
 	}
 
 	if len(rgOutputs) > 0 && status == "FOUND" {
-		// If matches were found, include location information
+		// If matches were found, include location information and the IDs of the patterns found
+		matcher := newPatternMatcher(policy.Regex)
+		var patternIDs []string
 		for _, rgOutput := range rgOutputs {
 			if rgOutput.Type == "match" {
+				for _, submatch := range rgOutput.Data.Submatches {
+					if pattern := matcher.match(submatch.Pattern); pattern != nil && pattern.ID != "" {
+						patternIDs = appendUnique(patternIDs, pattern.ID)
+					}
+				}
 				result.Locations = append(result.Locations, Location{
 					PhysicalLocation: PhysicalLocation{
 						ArtifactLocation: ArtifactLocation{URI: rgOutput.Data.Path.Text},
@@ -524,6 +649,7 @@ func GenerateAssureSARIFReport(inputFile string, policy Policy, status string) (
 				})
 			}
 		}
+		result.Properties.PatternID = strings.Join(patternIDs, ",")
 	} else {
 		// If no matches were found or status is "NOT FOUND", we don't have specific location information
 		result.Locations = []Location{
@@ -545,6 +671,8 @@ func GenerateAssureSARIFReport(inputFile string, policy Policy, status string) (
 
 	sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, result)
 
+	sarifReport.Runs[0].Results = applyExceptions(virtualizeResultURIs(sarifReport.Runs[0].Results))
+	sarifReport.Runs[0].Results = redactResults(policy, sarifReport.Runs[0].Results)
 	sarifReport.Runs[0].Invocations[0].Properties.ReportCompliant = ComplianceStatus(sarifReport)
 
 	if outputTypeMatrixConfig.LOG {
@@ -669,6 +797,7 @@ func GenerateSchemaSARIFReport(policy Policy, filePath string, valid bool, issue
 	}
 	sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, summaryResult)
 
+	sarifReport.Runs[0].Results = applyExceptions(virtualizeResultURIs(sarifReport.Runs[0].Results))
 	sarifReport.Runs[0].Invocations[0].Properties.ReportCompliant = ComplianceStatus(sarifReport)
 
 	if outputTypeMatrixConfig.LOG {
@@ -700,6 +829,14 @@ func writeSARIFReport(policyID string, report SARIFReport) error {
 	}
 	defer file.Close()
 
+	if counts := selectedFiles(policyID); counts != nil {
+		for i := range report.Runs {
+			for j := range report.Runs[i].Invocations {
+				report.Runs[i].Invocations[j].Properties.SelectedFiles = counts
+			}
+		}
+	}
+
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
 	if err := encoder.Encode(report); err != nil {
@@ -783,16 +920,53 @@ func MergeSARIFReports(commandLine string, perf Performance, isScheduled bool) (
 
 		for _, run := range report.Runs {
 			mergedReport.Runs[0].Results = append(mergedReport.Runs[0].Results, run.Results...)
-
-			for _, result := range run.Results {
-				if result.Level == SARIFWarning || result.Level == SARIFError {
-					isCompliant = false
-					break
+			for _, invocation := range run.Invocations {
+				for policyID, count := range invocation.Properties.SelectedFiles {
+					if mergedReport.Runs[0].Invocations[0].Properties.SelectedFiles == nil {
+						mergedReport.Runs[0].Invocations[0].Properties.SelectedFiles = make(map[string]int)
+					}
+					mergedReport.Runs[0].Invocations[0].Properties.SelectedFiles[policyID] = count
+				}
+				// several license policies usually share the target, keep the highest count
+				for license, count := range invocation.Properties.LicenseInventory {
+					if mergedReport.Runs[0].Invocations[0].Properties.LicenseInventory == nil {
+						mergedReport.Runs[0].Invocations[0].Properties.LicenseInventory = make(map[string]int)
+					}
+					mergedReport.Runs[0].Invocations[0].Properties.LicenseInventory[license] = max(mergedReport.Runs[0].Invocations[0].Properties.LicenseInventory[license], count)
 				}
 			}
 		}
 	}
 
+	mergedReport.Runs[0].Results = applyBaseline(mergedReport.Runs[0].Results)
+
+	for _, result := range mergedReport.Runs[0].Results {
+		switch result.BaselineState {
+		case BaselineStateNew:
+			mergedReport.Runs[0].Invocations[0].Properties.NewResults++
+		case BaselineStateUnchanged:
+			mergedReport.Runs[0].Invocations[0].Properties.UnchangedResults++
+		case BaselineStateAbsent:
+			mergedReport.Runs[0].Invocations[0].Properties.AbsentResults++
+		}
+		if result.Properties.Cached {
+			mergedReport.Runs[0].Invocations[0].Properties.CachedResults++
+		}
+		if isSuppressed(result) {
+			mergedReport.Runs[0].Invocations[0].Properties.SuppressedResults++
+			continue
+		}
+		if result.Properties.ExceptionStatus == ExceptionStatusExpired {
+			mergedReport.Runs[0].Invocations[0].Properties.ExpiredExceptions++
+		}
+		if isBaselineFinding(result) {
+			continue
+		}
+		if result.Level == SARIFWarning || result.Level == SARIFError {
+			isCompliant = false
+		}
+	}
+
 	// Set the compliance status
 	if isCompliant {
 		mergedReport.Runs[0].Invocations[0].Properties.ReportStatus = "compliant"
@@ -885,6 +1059,7 @@ func createSARIFReport(results []Result) SARIFReport {
 		},
 	}
 
+	sarifReport.Runs[0].Results = applyExceptions(virtualizeResultURIs(sarifReport.Runs[0].Results))
 	sarifReport.Runs[0].Invocations[0].Properties.ReportCompliant = ComplianceStatus(sarifReport)
 
 	if outputTypeMatrixConfig.LOG {
@@ -1014,6 +1189,7 @@ func GenerateAPISARIFReport(policy Policy, endpoint string, matchFound bool, iss
 		sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, issueResult)
 	}
 
+	sarifReport.Runs[0].Results = applyExceptions(virtualizeResultURIs(sarifReport.Runs[0].Results))
 	sarifReport.Runs[0].Invocations[0].Properties.ReportCompliant = ComplianceStatus(sarifReport)
 
 	if outputTypeMatrixConfig.LOG {
@@ -1026,6 +1202,9 @@ func GenerateAPISARIFReport(policy Policy, endpoint string, matchFound bool, iss
 func ComplianceStatus(sarifReport SARIFReport) bool {
 
 	for _, result := range sarifReport.Runs[0].Results {
+		if isSuppressed(result) {
+			continue
+		}
 		if result.Level == "error" {
 			return false
 		}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 )
@@ -25,14 +26,6 @@ func ProcessScanType(policy Policy, rgPath string, targetDir string, filePaths [
 }
 
 func executeScan(policy Policy, rgPath string, targetDir string, filesToScan []string) error {
-	// Create a temporary file to store the search patterns
-	searchPatternFile, err := createSearchPatternFile(policy.Regex, NormalizeFilename(policy.ID))
-	if err != nil {
-		log.Error().Err(err).Msg("error creating search pattern file")
-		return fmt.Errorf("error creating search pattern file: %w", err)
-	}
-	defer os.Remove(searchPatternFile) // Clean up the temporary file
-
 	// Prepare the ripgrep command for JSON output
 	jsonOutputFile := fmt.Sprintf("scan_results_%s.json", NormalizeFilename(policy.ID))
 
@@ -47,13 +40,12 @@ func executeScan(policy Policy, rgPath string, targetDir string, filesToScan []s
 		log.Error().Err(err).Msg("error creating JSON output file")
 		return fmt.Errorf("error creating JSON output file: %w", err)
 	}
+	defer redactDebugOutput(policy, jsonOutputFile)
 	defer jsonoutfile.Close()
 
 	writer := bufio.NewWriter(jsonoutfile)
 	defer writer.Flush()
 
-	processedIgnorePatterns := processIgnorePatterns(policyData.Config.Flags.Ignore)
-
 	codePatternScanJSON := []string{
 		"--pcre2",
 		"--no-heading",
@@ -62,21 +54,28 @@ func executeScan(policy Policy, rgPath string, targetDir string, filesToScan []s
 		"-i",
 		"-U",
 		"--json",
-		"-f", searchPatternFile,
 	}
-	codePatternScanJSON = append(codePatternScanJSON, processedIgnorePatterns...)
 
 	if targetDir == "" {
 		return fmt.Errorf("no target directory defined")
 	}
 
-	// Parallel execution for large file sets
-	if policy.FilePattern == "" {
-		err = executeSingleScan(rgPath, codePatternScanJSON, nil, targetDir, policy, writer)
-	} else if len(filesToScan) > 25 {
-		err = executeParallelScans(rgPath, codePatternScanJSON, filesToScan, writer)
+	// both engines search the files of the target walk, so they honour the same ignore rules
+	if searchesWholeTarget(policy) {
+		filesToScan = wholeTargetFiles(targetDir, filesToScan)
+	}
+
+	if useNativeEngine() {
+		_, err = nativeSearch(regexPatternStrings(policy.Regex), filesToScan, writer)
 	} else {
-		err = executeSingleScan(rgPath, codePatternScanJSON, filesToScan, targetDir, policy, writer)
+		_, err = searchEachPattern(policy, func(patternFile string, writer *bufio.Writer) (bool, error) {
+			args := slices.Clip(append(slices.Clip(codePatternScanJSON), "-f", patternFile))
+			// Parallel execution for large file sets
+			if len(filesToScan) > 25 {
+				return true, executeParallelScans(rgPath, args, filesToScan, writer)
+			}
+			return true, executeSingleScan(rgPath, args, filesToScan, targetDir, policy, writer)
+		}, writer)
 	}
 
 	if err != nil {
@@ -84,6 +83,10 @@ func executeScan(policy Policy, rgPath string, targetDir string, filesToScan []s
 		log.Error().Err(err).Msg("error executing ripgrep batch")
 	}
 
+	if err := writer.Flush(); err != nil {
+		log.Error().Err(err).Msg("error writing JSON output file")
+	}
+
 	// Patch the JSON output file
 	err = patchJSONOutputFile(jsonOutputFile)
 	if err != nil {
@@ -207,13 +210,12 @@ func executeParallelScans(rgPath string, baseArgs []string, filesToScan []string
 }
 
 func executeSingleScan(rgPath string, baseArgs []string, filesToScan []string, targetDir string, policy Policy, writer *bufio.Writer) error {
-	if len(filesToScan) > 0 {
-		baseArgs = append(baseArgs, filesToScan...)
-	} else if policy.FilePattern == "" {
-		baseArgs = append(baseArgs, targetDir)
-	} else {
-		log.Error().Str("policy", policy.ID).Msgf("no files matched policy pattern on target : %s", targetDir)
+	if len(filesToScan) == 0 {
+		// ripgrep would search the working directory
+		log.Error().Str("policy", policy.ID).Msgf("no files selected by the policy on target : %s", targetDir)
+		return nil
 	}
+	baseArgs = append(baseArgs, filesToScan...)
 
 	// log.Debug().Msgf("RGS: %v", baseArgs)
 
@@ -3,51 +3,67 @@ package cmd
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"cuelang.org/go/cue/cuecontext"
 	"cuelang.org/go/cue/errors"
 )
 
-func validateContentAndCUE(content []byte, cueContent string, contentType string, strictSchema bool, policyID string) (bool, []string) {
-	var issues []string
+// schemaIssue is a validation issue, path locates it in the validated document when known
+type schemaIssue struct {
+	path    []string
+	message string
+}
 
+func validateContentAndCUE(content []byte, cueContent string, contentType string, strictSchema bool, policyID string) (bool, []string) {
 	// Convert content to JSON (implementation depends on contentType)
 	jsonContent, err := convertToJSON(content, contentType)
 	if err != nil {
-		issues = append(issues, fmt.Sprintf("Error converting %s to JSON: %v", contentType, err))
-		return false, issues
+		return false, []string{fmt.Sprintf("Error converting %s to JSON: %v", contentType, err)}
 	}
 
+	valid, issues := validateJSONWithCUE(jsonContent, cueContent, strictSchema, policyID)
+	var messages []string
+	for _, issue := range issues {
+		messages = append(messages, issue.message)
+	}
+	return valid, messages
+}
+
+// validateJSONWithCUE validates one JSON document against a CUE structure
+func validateJSONWithCUE(jsonContent []byte, cueContent string, strictSchema bool, policyID string) (bool, []schemaIssue) {
+	var issues []schemaIssue
+
 	ctx := cuecontext.New()
 	cueValue := ctx.CompileString(cueContent)
 	if cueValue.Err() != nil {
-		issues = append(issues, fmt.Sprintf("Error compiling CUE content: %v", cueValue.Err()))
+		issues = append(issues, schemaIssue{message: fmt.Sprintf("Error compiling CUE content: %v", cueValue.Err())})
 		return false, issues
 	}
 
 	jsonCueValue := ctx.CompileBytes(jsonContent)
 	if jsonCueValue.Err() != nil {
-		issues = append(issues, fmt.Sprintf("Error compiling JSON data to CUE value: %v", jsonCueValue.Err()))
+		issues = append(issues, schemaIssue{message: fmt.Sprintf("Error compiling JSON data to CUE value: %v", jsonCueValue.Err())})
 		return false, issues
 	}
 
 	unified := cueValue.Unify(jsonCueValue)
 	if err := unified.Validate(); err != nil {
-		errors := extractCUEErrors(err)
-		issues = append(issues, errors...)
+		issues = append(issues, cueSchemaIssues(err)...)
 	}
 
 	missingFields, extraFields := validateSchema(cueValue, jsonCueValue)
 
 	// missingFields := findMissingFields(cueValue, jsonCueValue)
 	for _, field := range missingFields {
-		issues = append(issues, fmt.Sprintf("Missing required field: %s", field))
+		issues = append(issues, schemaIssue{path: strings.Split(field, "."), message: fmt.Sprintf("Missing required field: %s", field)})
 	}
 
 	if strictSchema {
 		// extraFields := findExtraFields(cueValue, jsonCueValue)
 		for _, field := range extraFields {
-			issues = append(issues, fmt.Sprintf("Extra field not defined in schema: %s", field))
+			issues = append(issues, schemaIssue{path: strings.Split(field, "."), message: fmt.Sprintf("Extra field not defined in schema: %s", field)})
 		}
 	}
 
@@ -59,12 +75,32 @@ func validateContentAndCUE(content []byte, cueContent string, contentType string
 
 func extractCUEErrors(err error) []string {
 	var errs []string
-	for _, e := range errors.Errors(err) {
-		errs = append(errs, fmt.Sprintf("Validation error at %v: %v", e.Path(), e.Error()))
+	for _, issue := range cueSchemaIssues(err) {
+		errs = append(errs, issue.message)
 	}
 	return errs
 }
 
+func cueSchemaIssues(err error) []schemaIssue {
+	var issues []schemaIssue
+	for _, e := range errors.Errors(err) {
+		issues = append(issues, schemaIssue{path: cueLabels(e.Path()), message: fmt.Sprintf("Validation error at %v: %v", e.Path(), e.Error())})
+	}
+	return issues
+}
+
+// cueLabels unquotes the labels of a CUE error path that are not identifiers ("app.kubernetes.io/name")
+func cueLabels(path []string) []string {
+	labels := make([]string, len(path))
+	for i, label := range path {
+		if unquoted, err := strconv.Unquote(label); err == nil && strings.HasPrefix(label, `"`) {
+			label = unquoted
+		}
+		labels[i] = label
+	}
+	return labels
+}
+
 // This function is shared across all policy types
 func generateSchemaResults(policy Policy, filePath string, valid bool, issues []string, patched bool) []Result {
 	var results []Result
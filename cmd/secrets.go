@@ -0,0 +1,383 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	secretsDefaultEntropy = 3.5
+
+	checksumGitHub = "github"
+	checksumLuhn   = "luhn"
+)
+
+// secretChecksums validate a candidate against the checksum embedded in known token formats
+var secretChecksums = map[string]func(string) bool{
+	checksumGitHub: validGitHubTokenChecksum,
+	checksumLuhn:   validLuhn,
+}
+
+// secretDetectors are the built-in candidates of secrets policies, selected by name in _secrets.detectors
+var secretDetectors = map[string]RegexPattern{
+	"github": {
+		ID:          "github-token",
+		Pattern:     `\b(gh[pousr]_[A-Za-z0-9]{36})\b`,
+		Description: "GitHub token",
+		Checksum:    checksumGitHub,
+	},
+	"aws": {
+		ID:          "aws-access-key",
+		Pattern:     `\b((?:AKIA|ASIA)[A-Z0-9]{16})\b`,
+		Description: "AWS access key ID",
+		Entropy:     3.0,
+	},
+	"card": {
+		ID:          "payment-card",
+		Pattern:     `\b((?:\d[ -]?){12,18}\d)\b`,
+		Description: "Payment card number",
+		Checksum:    checksumLuhn,
+	},
+	"private-key": {
+		ID:          "private-key",
+		Pattern:     `-----BEGIN (?:RSA |DSA |EC |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY(?: BLOCK)?-----`,
+		Description: "Private key",
+	},
+	"slack": {
+		ID:          "slack-token",
+		Pattern:     `\b(xox[abprs]-[A-Za-z0-9-]{10,})\b`,
+		Description: "Slack token",
+	},
+	"generic": {
+		ID:          "generic-secret",
+		Pattern:     `(?i)(?:secret|token|passw(?:or)?d|api[_-]?key|access[_-]?key)[\w.-]*\s*[:=]\s*["']?([^\s"'#,;]{8,})`,
+		Description: "Generic secret assignment",
+	},
+}
+
+// secretCandidate is a compiled detector of a secrets policy
+type secretCandidate struct {
+	pattern RegexPattern
+	re      *regexp.Regexp
+	entropy float64
+}
+
+type secretFinding struct {
+	file     string
+	region   Region
+	verified bool
+	pattern  *RegexPattern
+}
+
+// ProcessSecretsType handles policies of type "secrets", they always run on the native engine
+func ProcessSecretsType(policy Policy, targetDir string, filePaths []string) error {
+	if policy.Type != "secrets" {
+		return nil
+	}
+
+	findings, err := executeSecrets(policy, targetDir, filePaths)
+	if err != nil {
+		log.Error().Err(err).Msgf("error detecting secrets for policy %s", policy.ID)
+		return fmt.Errorf("error detecting secrets for policy %s: %w", policy.ID, err)
+	}
+
+	sarifReport := GenerateSecretsSARIFReport(policy, findings)
+
+	reportID := policy.ID
+	if policy.RunID != "" {
+		reportID = policy.RunID
+	}
+	if err := writeSARIFReport(reportID, sarifReport); err != nil {
+		log.Error().Err(err).Msg("error writing SARIF report")
+		return fmt.Errorf("error writing SARIF report: %w", err)
+	}
+
+	log.Debug().Msgf("Policy %s processed. %d secrets found", policy.ID, len(findings))
+	return nil
+}
+
+func executeSecrets(policy Policy, targetDir string, filePaths []string) ([]secretFinding, error) {
+	candidates, err := compileSecretCandidates(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	allowPatterns := make([]*regexp.Regexp, 0, len(policy.Secrets.Allowlist.Patterns))
+	for _, pattern := range policy.Secrets.Allowlist.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist pattern %q: %w", pattern, err)
+		}
+		allowPatterns = append(allowPatterns, re)
+	}
+
+	files := filePaths
+	if searchesWholeTarget(policy) {
+		if targetDir == "" {
+			return nil, fmt.Errorf("no target directory defined")
+		}
+		files = wholeTargetFiles(targetDir, filePaths)
+	}
+
+	var findings []secretFinding
+	for _, file := range files {
+		if secretPathAllowed(policy.Secrets.Allowlist.Paths, file) {
+			log.Debug().Str("policy", policy.ID).Str("file", file).Msg("file allowlisted")
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Debug().Err(err).Str("file", file).Msg("secrets skipped unreadable file")
+			continue
+		}
+		if bytes.IndexByte(content[:min(len(content), nativeBinaryProbe)], 0) >= 0 {
+			continue
+		}
+		findings = append(findings, detectSecrets(candidates, allowPatterns, file, content)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].file != findings[j].file {
+			return findings[i].file < findings[j].file
+		}
+		return *findings[i].region.ByteOffset < *findings[j].region.ByteOffset
+	})
+
+	return findings, nil
+}
+
+// compileSecretCandidates merges the _regex entries of the policy with the selected built-in detectors.
+// Without either, all built-in detectors are used.
+func compileSecretCandidates(policy Policy) ([]secretCandidate, error) {
+	patterns := append([]RegexPattern(nil), policy.Regex...)
+
+	names := policy.Secrets.Detectors
+	if len(names) == 0 && len(patterns) == 0 {
+		names = secretDetectorNames()
+	}
+	for _, name := range names {
+		detector, ok := secretDetectors[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown secrets detector %q (expected one of %s)", name, strings.Join(secretDetectorNames(), ","))
+		}
+		patterns = append(patterns, detector)
+	}
+
+	candidates := make([]secretCandidate, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q is not a valid RE2 expression: %w", pattern.Pattern, err)
+		}
+		if pattern.Checksum != "" {
+			if _, ok := secretChecksums[strings.ToLower(pattern.Checksum)]; !ok {
+				return nil, fmt.Errorf("unknown checksum %q (expected %s or %s)", pattern.Checksum, checksumGitHub, checksumLuhn)
+			}
+		}
+
+		// entropy precedence : pattern, policy, default. Checksum formats rely on the checksum instead,
+		// and patterns without a capture group (e.g. key headers) only apply an explicit entropy.
+		entropy := pattern.Entropy
+		if entropy == 0 && pattern.Checksum == "" && re.NumSubexp() > 0 {
+			entropy = policy.Secrets.Entropy
+			if entropy == 0 {
+				entropy = secretsDefaultEntropy
+			}
+		}
+		candidates = append(candidates, secretCandidate{pattern: pattern, re: re, entropy: entropy})
+	}
+	return candidates, nil
+}
+
+func detectSecrets(candidates []secretCandidate, allowPatterns []*regexp.Regexp, file string, content []byte) []secretFinding {
+	var findings []secretFinding
+	seen := make(map[int]bool)
+	text := string(content)
+
+	for i := range candidates {
+		candidate := &candidates[i]
+		for _, m := range candidate.re.FindAllSubmatchIndex(content, -1) {
+			// the secret is the first capture group when the pattern has one
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if start == end || seen[start] {
+				continue
+			}
+			secret := string(content[start:end])
+			matchText := string(content[m[0]:m[1]])
+
+			if secretAllowed(allowPatterns, secret, matchText) {
+				continue
+			}
+
+			verified := false
+			if candidate.pattern.Checksum != "" {
+				if !secretChecksums[strings.ToLower(candidate.pattern.Checksum)](secret) {
+					continue
+				}
+				verified = true
+			}
+			if candidate.entropy > 0 && shannonEntropy(secret) < candidate.entropy {
+				continue
+			}
+
+			seen[start] = true
+			findings = append(findings, secretFinding{
+				file:     file,
+				region:   matchRegion(text, 1, 0, m[0], m[1]),
+				verified: verified,
+				pattern:  &candidate.pattern,
+			})
+		}
+	}
+	return findings
+}
+
+// GenerateSecretsSARIFReport creates one result per secret, or a compliant note when none is found
+func GenerateSecretsSARIFReport(policy Policy, findings []secretFinding) SARIFReport {
+	sarifReport := newPolicySARIFReport(policy)
+
+	sarifLevel := calculateSARIFLevel(policy, environment)
+	properties := policyResultProperties(policy)
+
+	if len(findings) == 0 {
+		sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, compliantResult(policy, "No secrets found"))
+	}
+
+	sources := sourceLines{}
+	for _, finding := range findings {
+		verified := finding.verified
+		status := "unverified"
+		if verified {
+			status = "verified"
+		}
+
+		result := Result{
+			RuleID: policy.ID,
+			Level:  sarifLevel,
+			Message: Message{
+				Text: fmt.Sprintf("Policy violation: %s%s (%s) Matched text: %s", policy.Metadata.Name, patternLabel(finding.pattern), status, finding.region.Snippet.Text),
+			},
+			Locations: []Location{{PhysicalLocation: PhysicalLocation{
+				ArtifactLocation: ArtifactLocation{URI: filepath.ToSlash(finding.file)},
+				Region:           finding.region,
+				ContextRegion:    sources.contextRegion(finding.file, finding.region, policy.ContextLines),
+			}}},
+			Properties: properties,
+		}
+		result.Properties.SarifInt = sarifLevelToInt(sarifLevel)
+		result.Properties.Verified = &verified
+		applyPatternToResult(&result, finding.pattern)
+
+		sarifReport.Runs[0].Results = append(sarifReport.Runs[0].Results, result)
+	}
+
+	sarifReport.Runs[0].Results = applyExceptions(virtualizeResultURIs(sarifReport.Runs[0].Results))
+	sarifReport.Runs[0].Results = redactResults(policy, sarifReport.Runs[0].Results)
+	sarifReport.Runs[0].Invocations[0].Properties.ReportCompliant = ComplianceStatus(sarifReport)
+
+	if outputTypeMatrixConfig.LOG {
+		PostResultsToComplianceLog(sarifReport)
+	}
+
+	return sarifReport
+}
+
+func secretPathAllowed(globs []string, file string) bool {
+	for _, glob := range globs {
+		if matchExceptionPath(glob, filepath.ToSlash(file)) {
+			return true
+		}
+	}
+	return false
+}
+
+func secretAllowed(allowPatterns []*regexp.Regexp, secret, matchText string) bool {
+	for _, re := range allowPatterns {
+		if re.MatchString(secret) || re.MatchString(matchText) {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// validGitHubTokenChecksum checks the last 6 characters of a gh*_ token, the base62 CRC32 of its 30 random characters
+func validGitHubTokenChecksum(token string) bool {
+	_, body, ok := strings.Cut(token, "_")
+	if !ok || len(body) != 36 {
+		return false
+	}
+	crc := crc32.ChecksumIEEE([]byte(body[:30]))
+
+	encoded := make([]byte, 6)
+	for i := len(encoded) - 1; i >= 0; i-- {
+		encoded[i] = base62Alphabet[crc%62]
+		crc /= 62
+	}
+	return string(encoded) == body[30:]
+}
+
+// validLuhn checks a card number, separators are ignored
+func validLuhn(number string) bool {
+	sum, digits := 0, 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
+func secretDetectorNames() []string {
+	names := make([]string, 0, len(secretDetectors))
+	for name := range secretDetectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fileSelection holds the target file criteria of a policy besides filepattern,
+// globs are doublestar patterns matched against paths relative to the target
+type fileSelection struct {
+	include     []*regexp.Regexp
+	exclude     []*regexp.Regexp
+	ignore      []ignoreRule
+	maxFileSize int64
+	maxDepth    int
+	skipBinary  bool
+}
+
+var (
+	selectedFilesMutex sync.Mutex
+	selectedFileCounts = make(map[string]map[string]int)
+)
+
+// validateFileSelections compiles the filepattern and file selection of every policy before an audit,
+// a policy with an invalid selection would otherwise check no files and report compliant
+func validateFileSelections(policies []Policy) error {
+	for _, policy := range policies {
+		if policy.FilePattern != "" {
+			if _, err := regexp.Compile(policy.FilePattern); err != nil {
+				return fmt.Errorf("policy %s: invalid filepattern: %w", policy.ID, err)
+			}
+		}
+		if _, err := compileFileSelection(policy); err != nil {
+			return fmt.Errorf("policy %s: invalid file selection: %w", policy.ID, err)
+		}
+	}
+	return nil
+}
+
+// compileFileSelection returns nil when the policy only relies on filepattern
+func compileFileSelection(policy Policy) (*fileSelection, error) {
+	if len(policy.Include) == 0 && len(policy.Exclude) == 0 && len(policy.Ignore) == 0 &&
+		policy.MaxFileSize == "" && policy.MaxDepth == 0 && !policy.SkipBinary {
+		return nil, nil
+	}
+
+	selection := &fileSelection{maxDepth: policy.MaxDepth, skipBinary: policy.SkipBinary}
+	var err error
+	if selection.include, err = compileGlobs(policy.Include); err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	if selection.exclude, err = compileGlobs(policy.Exclude); err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+	if selection.ignore, err = compileIgnoreRules("", policy.Ignore); err != nil {
+		return nil, fmt.Errorf("ignore: %w", err)
+	}
+	if selection.maxFileSize, err = parseFileSize(policy.MaxFileSize); err != nil {
+		return nil, fmt.Errorf("max_file_size: %w", err)
+	}
+	if policy.MaxDepth < 0 {
+		return nil, fmt.Errorf("max_depth must not be negative")
+	}
+	return selection, nil
+}
+
+// filter keeps the files matching an include glob and no exclude glob, within the depth and size limits
+func (s *fileSelection) filter(targetDir string, fileInfos []FileInfo) []FileInfo {
+	var selected []FileInfo
+	for _, fileInfo := range fileInfos {
+		if s.selects(targetDir, fileInfo.Path) {
+			selected = append(selected, fileInfo)
+		}
+	}
+	return selected
+}
+
+func (s *fileSelection) selects(targetDir, path string) bool {
+	rel, ok := targetRelPath(targetDir, path)
+	if !ok {
+		// a single file target is matched on its name
+		rel = filepath.Base(path)
+	}
+
+	if len(s.include) > 0 && !matchAnyGlob(s.include, rel) {
+		return false
+	}
+	if matchAnyGlob(s.exclude, rel) {
+		return false
+	}
+	if policyIgnored(s.ignore, targetDir, path) {
+		return false
+	}
+	if s.maxDepth > 0 && strings.Count(rel, "/")+1 > s.maxDepth {
+		return false
+	}
+	if s.maxFileSize > 0 {
+		info, err := os.Stat(path)
+		if err != nil || info.Size() > s.maxFileSize {
+			return false
+		}
+	}
+	if s.skipBinary && isBinaryFile(path) {
+		return false
+	}
+	return true
+}
+
+func compileGlobs(patterns []string) ([]*regexp.Regexp, error) {
+	var globs []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := compileGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, re)
+	}
+	return globs, nil
+}
+
+// compileGlob translates a doublestar glob : ** spans directories, * and ? stay within one,
+// [...] classes and {a,b} alternatives are supported
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	glob := strings.TrimPrefix(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), "/")
+	if glob == "" {
+		return nil, fmt.Errorf("empty glob")
+	}
+
+	var alternatives []string
+	for _, expanded := range expandBraces(glob) {
+		expr, err := ignorePatternRegexp(expanded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		alternatives = append(alternatives, expr)
+	}
+	re, err := regexp.Compile("^(?:" + strings.Join(alternatives, "|") + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// expandBraces expands the first {a,b} group of a glob, recursively
+func expandBraces(glob string) []string {
+	start := strings.IndexByte(glob, '{')
+	if start < 0 {
+		return []string{glob}
+	}
+	depth := 0
+	for end := start; end < len(glob); end++ {
+		switch glob[end] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			var expanded []string
+			for _, option := range splitBraceOptions(glob[start+1 : end]) {
+				expanded = append(expanded, expandBraces(glob[:start]+option+glob[end+1:])...)
+			}
+			return expanded
+		}
+	}
+	return []string{glob}
+}
+
+// splitBraceOptions splits on the commas that are not inside a nested group
+func splitBraceOptions(group string) []string {
+	var options []string
+	depth, last := 0, 0
+	for i := 0; i < len(group); i++ {
+		switch group[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				options = append(options, group[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(options, group[last:])
+}
+
+// compiledGlobs caches the globs matched one path at a time (exception paths, secrets allowlists)
+var compiledGlobs sync.Map
+
+// matchGlob reports whether a slash separated path matches a glob with the semantics of include and
+// exclude, an invalid glob matches nothing
+func matchGlob(pattern, name string) bool {
+	re, ok := compiledGlobs.Load(pattern)
+	if !ok {
+		compiled, err := compileGlob(pattern)
+		if err != nil {
+			log.Debug().Err(err).Str("glob", pattern).Msg("invalid glob matches nothing")
+		}
+		re, _ = compiledGlobs.LoadOrStore(pattern, compiled)
+	}
+	compiled := re.(*regexp.Regexp)
+	return compiled != nil && compiled.MatchString(strings.TrimPrefix(strings.TrimPrefix(filepath.ToSlash(name), "./"), "/"))
+}
+
+func matchAnyGlob(globs []*regexp.Regexp, rel string) bool {
+	for _, glob := range globs {
+		if glob.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFileSize reads sizes like 512, 64KB, 10MB or 1GB (binary multiples), empty means no limit
+func parseFileSize(size string) (int64, error) {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	if size == "" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		bytes  int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(size, unit.suffix) {
+			multiplier = unit.bytes
+			size = strings.TrimSpace(strings.TrimSuffix(size, unit.suffix))
+			break
+		}
+	}
+	value, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid file size %q", size)
+	}
+	return value * multiplier, nil
+}
+
+// isBinaryFile checks the leading bytes for NUL like the scan engines, unreadable files count as binary
+func isBinaryFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer file.Close()
+
+	probe := make([]byte, nativeBinaryProbe)
+	n, err := io.ReadFull(file, probe)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return true
+	}
+	return bytes.IndexByte(probe[:n], 0) >= 0
+}
+
+// recordSelectedFiles keeps the number of files selected by a policy for its SARIF invocation properties
+func recordSelectedFiles(policy Policy, count int) {
+	selectedFilesMutex.Lock()
+	defer selectedFilesMutex.Unlock()
+	selectedFileCounts[sarifReportID(policy)] = map[string]int{policy.ID: count}
+}
+
+func selectedFiles(reportID string) map[string]int {
+	selectedFilesMutex.Lock()
+	defer selectedFilesMutex.Unlock()
+	return selectedFileCounts[NormalizeFilename(reportID)]
+}
+
+// sarifReportID is the name of the SARIF file of a policy
+func sarifReportID(policy Policy) string {
+	if policy.RunID != "" {
+		return NormalizeFilename(policy.RunID)
+	}
+	return NormalizeFilename(policy.ID)
+}
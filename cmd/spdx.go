@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// licenseExpression is a parsed SPDX license expression : a license with its optional WITH exception,
+// or the AND / OR of its operands
+type licenseExpression struct {
+	operator  string
+	license   string
+	exception string
+	operands  []licenseExpression
+}
+
+// parseLicenseExpression parses an SPDX license expression, AND binds tighter than OR and operators are
+// case-insensitive. Text that is not a valid expression ("Apache License 2.0") is a single license.
+func parseLicenseExpression(expression string) licenseExpression {
+	expression = strings.TrimSpace(expression)
+	parser := &licenseExpressionParser{tokens: tokenizeLicenseExpression(expression)}
+	parsed, err := parser.parseOr()
+	if err == nil && parser.pos < len(parser.tokens) {
+		err = fmt.Errorf("unexpected %q", parser.tokens[parser.pos])
+	}
+	if err != nil {
+		log.Debug().Err(err).Str("expression", expression).Msg("license expression read as a single license")
+		return licenseExpression{license: expression}
+	}
+	return parsed
+}
+
+// evaluate folds the expression with the rank of each license : OR keeps the lowest rank of its
+// operands (the best alternative) and AND the highest (the worst part)
+func (e licenseExpression) evaluate(rank func(license, exception string) int) int {
+	if e.operator == "" {
+		return rank(e.license, e.exception)
+	}
+	result := e.operands[0].evaluate(rank)
+	for _, operand := range e.operands[1:] {
+		value := operand.evaluate(rank)
+		if e.operator == "OR" {
+			result = min(result, value)
+		} else {
+			result = max(result, value)
+		}
+	}
+	return result
+}
+
+func tokenizeLicenseExpression(expression string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(expression) {
+		for field != "" {
+			if i := strings.IndexAny(field, "()"); i != 0 {
+				if i < 0 {
+					i = len(field)
+				}
+				tokens = append(tokens, field[:i])
+				field = field[i:]
+				continue
+			}
+			tokens = append(tokens, field[:1])
+			field = field[1:]
+		}
+	}
+	return tokens
+}
+
+type licenseExpressionParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *licenseExpressionParser) peekOperator(operator string) bool {
+	return p.pos < len(p.tokens) && strings.EqualFold(p.tokens[p.pos], operator)
+}
+
+func (p *licenseExpressionParser) parseOr() (licenseExpression, error) {
+	return p.parseBinary("OR", p.parseAnd)
+}
+
+func (p *licenseExpressionParser) parseAnd() (licenseExpression, error) {
+	return p.parseBinary("AND", p.parseWith)
+}
+
+func (p *licenseExpressionParser) parseBinary(operator string, operand func() (licenseExpression, error)) (licenseExpression, error) {
+	first, err := operand()
+	if err != nil {
+		return licenseExpression{}, err
+	}
+	operands := []licenseExpression{first}
+	for p.peekOperator(operator) {
+		p.pos++
+		next, err := operand()
+		if err != nil {
+			return licenseExpression{}, err
+		}
+		operands = append(operands, next)
+	}
+	if len(operands) == 1 {
+		return first, nil
+	}
+	return licenseExpression{operator: operator, operands: operands}, nil
+}
+
+func (p *licenseExpressionParser) parseWith() (licenseExpression, error) {
+	expression, err := p.parsePrimary()
+	if err != nil || !p.peekOperator("WITH") {
+		return expression, err
+	}
+	if expression.operator != "" {
+		return licenseExpression{}, fmt.Errorf("WITH applies to a license")
+	}
+	p.pos++
+	exception, err := p.parseIdentifier()
+	if err != nil {
+		return licenseExpression{}, err
+	}
+	expression.exception = exception
+	return expression, nil
+}
+
+func (p *licenseExpressionParser) parsePrimary() (licenseExpression, error) {
+	if p.pos < len(p.tokens) && p.tokens[p.pos] == "(" {
+		p.pos++
+		expression, err := p.parseOr()
+		if err != nil {
+			return licenseExpression{}, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos] != ")" {
+			return licenseExpression{}, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return expression, nil
+	}
+	license, err := p.parseIdentifier()
+	return licenseExpression{license: license}, err
+}
+
+func (p *licenseExpressionParser) parseIdentifier() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", fmt.Errorf("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	if token == "(" || token == ")" || strings.EqualFold(token, "AND") || strings.EqualFold(token, "OR") || strings.EqualFold(token, "WITH") {
+		return "", fmt.Errorf("unexpected %q", token)
+	}
+	p.pos++
+	return token, nil
+}
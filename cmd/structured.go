@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+// structuredViolation is a failed check of a parsed file (dockerfile, hcl), located by the CUE path
+// of the failing field or by the line or address returned by the Rego module
+type structuredViolation struct {
+	path    []string
+	line    int
+	endLine int
+	address string
+	message string
+}
+
+// structuredEvaluator checks the generic value of one parsed file
+type structuredEvaluator func(input map[string]interface{}) ([]structuredViolation, error)
+
+// structuredInput converts a parsed file to the generic value given to CUE and Rego
+func structuredInput(parsed interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(parsed)
+	if err != nil {
+		return nil, err
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// compileStructuredEvaluator compiles the _rego module, or else the _schema CUE structure, of a policy
+func compileStructuredEvaluator(policy Policy) (structuredEvaluator, error) {
+	if policy.Rego.PolicyFile != "" {
+		return compileRegoEvaluator(policy)
+	}
+	if policy.Schema.Structure == "" {
+		return nil, fmt.Errorf("%s policies require a _schema.structure or a _rego section", policy.Type)
+	}
+
+	ctx := cuecontext.New()
+	schema := ctx.CompileString(policy.Schema.Structure)
+	if schema.Err() != nil {
+		return nil, fmt.Errorf("error compiling CUE content: %w", schema.Err())
+	}
+	return func(input map[string]interface{}) ([]structuredViolation, error) {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return nil, err
+		}
+		value := ctx.CompileBytes(data)
+		if value.Err() != nil {
+			return nil, value.Err()
+		}
+		var violations []structuredViolation
+		seen := make(map[string]bool)
+		for _, e := range collectCUEErrors(schema.Unify(value)) {
+			message := strings.TrimSpace(e.Error())
+			if seen[message] {
+				continue
+			}
+			seen[message] = true
+			violations = append(violations, structuredViolation{path: cueLabels(e.Path()), message: message})
+		}
+		return violations, nil
+	}, nil
+}
+
+// collectCUEErrors validates the fields of a value one by one, CUE drops incomplete values
+// (missing fields) from a validation that also has conflicts
+func collectCUEErrors(value cue.Value) []cueerrors.Error {
+	err := value.Validate(cue.Concrete(true))
+	if err == nil {
+		return nil
+	}
+
+	// values holding errors are bottom, their kind doesn't tell structs from lists
+	var errs []cueerrors.Error
+	if fields, iterErr := value.Fields(); iterErr == nil {
+		for fields.Next() {
+			errs = append(errs, collectCUEErrors(fields.Value())...)
+		}
+	} else if items, iterErr := value.List(); iterErr == nil {
+		for items.Next() {
+			errs = append(errs, collectCUEErrors(items.Value())...)
+		}
+	}
+	if len(errs) == 0 {
+		errs = cueerrors.Errors(err)
+	}
+	return errs
+}
+
+var regoPackagePath = regexp.MustCompile(`(?m)^package\s+([\w.]+)`)
+
+// compileRegoEvaluator queries data.<package> like rego policies, violations are strings or
+// {msg, line, end_line, address} objects
+func compileRegoEvaluator(policy Policy) (structuredEvaluator, error) {
+	policyContent, err := os.ReadFile(policy.Rego.PolicyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading policy file %s: %w", policy.Rego.PolicyFile, err)
+	}
+	packageName, err := extractPackageName(string(policyContent))
+	if err != nil {
+		return nil, err
+	}
+	if policy.Rego.PolicyQuery != "" {
+		if queryPackage := extractQueryPackage(policy.Rego.PolicyQuery); queryPackage != packageName {
+			return nil, fmt.Errorf("policy query package (%s) does not match Rego file package (%s)", queryPackage, packageName)
+		}
+	}
+	policyData := map[string]interface{}{}
+	if policy.Rego.PolicyData != "" {
+		if policyData, err = readJSONFile(policy.Rego.PolicyData); err != nil {
+			return nil, fmt.Errorf("error reading policy data file %s: %w", policy.Rego.PolicyData, err)
+		}
+	}
+
+	// nested packages (package terraform.modules) are queried as a whole
+	if matches := regoPackagePath.FindStringSubmatch(string(policyContent)); matches != nil {
+		packageName = matches[1]
+	}
+
+	ctx := context.Background()
+	query, err := rego.New(
+		rego.Query(fmt.Sprintf("data.%s", packageName)),
+		rego.Module("policy.rego", string(policyContent)),
+		rego.Store(inmem.NewFromObject(policyData)),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing query: %w", err)
+	}
+
+	return func(input map[string]interface{}) ([]structuredViolation, error) {
+		results, err := query.Eval(ctx, rego.EvalInput(input))
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating policy: %w", err)
+		}
+		return regoViolations(results)
+	}, nil
+}
+
+func regoViolations(results rego.ResultSet) ([]structuredViolation, error) {
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return nil, fmt.Errorf("no results returned from policy evaluation")
+	}
+	value, ok := results[0].Expressions[0].Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected result format from policy evaluation")
+	}
+
+	var violations []structuredViolation
+	items, _ := value["violations"].([]interface{})
+	for _, item := range items {
+		var violation structuredViolation
+		switch v := item.(type) {
+		case map[string]interface{}:
+			for _, key := range []string{"msg", "message"} {
+				if message, ok := v[key].(string); ok {
+					violation.message = message
+					break
+				}
+			}
+			if violation.message == "" {
+				violation.message = fmt.Sprint(v)
+			}
+			violation.line = regoInt(v["line"], 0)
+			violation.endLine = regoInt(v["end_line"], violation.line)
+			violation.address, _ = v["address"].(string)
+		default:
+			violation.message = fmt.Sprint(v)
+		}
+		violations = append(violations, violation)
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].line < violations[j].line })
+	if allow, ok := value["allow"].(bool); ok && !allow && len(violations) == 0 {
+		violations = append(violations, structuredViolation{message: "policy denied the input"})
+	}
+	return violations, nil
+}
+
+// regoInt reads the json.Number (or number) values of Rego results
+func regoInt(value interface{}, fallback int) int {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n)
+		}
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return fallback
+}
@@ -7,7 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/charlievieth/fastwalk"
 )
@@ -17,38 +20,36 @@ type FileInfo struct {
 	Hash string `json:"hash"`
 }
 
-// CalculateFileHashes recursively calculates SHA256 hashes for all files in the given directory
+// CalculateFileHashes recursively calculates SHA256 hashes for all files in the given directory,
+// files are listed by the concurrent walk then hashed by a pool of workers.
+// Paths matching the .gitignore and .interceptignore files of the target or Config.Flags.ignore are skipped.
 func CalculateFileHashes(targetDir string) ([]FileInfo, error) {
 
 	var fileInfos []FileInfo
+	var mu sync.Mutex
 
-	ignorePaths := policyData.Config.Flags.Ignore
+	ignore, err := newIgnoreMatcher(targetDir, policyData.Config.Flags.Ignore)
+	if err != nil {
+		return nil, err
+	}
 
-	err := fastwalk.Walk(nil, targetDir, func(path string, d fs.DirEntry, err error) error {
+	err = fastwalk.Walk(nil, targetDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if !d.IsDir() && !isIgnored(ignorePaths, path) {
-
-			if debugOutput {
-				hash, err := calculateSHA256(path)
-				if err != nil {
-					return err
-				}
-				fileInfos = append(fileInfos, FileInfo{
-					Path: path,
-					Hash: hash,
-				})
-			} else {
-
-				fileInfos = append(fileInfos, FileInfo{
-					Path: path,
-					Hash: "",
-				})
-
+		// parents are never ignored here, the walk skips ignored directories
+		if rel, ok := targetRelPath(targetDir, path); ok && ignore.matchPath(rel, d.IsDir()) {
+			if d.IsDir() {
+				return fastwalk.SkipDir
 			}
+			return nil
+		}
 
+		if !d.IsDir() {
+			mu.Lock()
+			fileInfos = append(fileInfos, FileInfo{Path: path})
+			mu.Unlock()
 		}
 
 		return nil
@@ -62,9 +63,41 @@ func CalculateFileHashes(targetDir string) ([]FileInfo, error) {
 		return nil, fmt.Errorf("no target files found at : %s", targetDir)
 	}
 
+	sort.Slice(fileInfos, func(i, j int) bool { return fileInfos[i].Path < fileInfos[j].Path })
+
+	hashFileInfos(fileInfos)
+
 	return fileInfos, nil
 }
 
+// hashFileInfos fills the SHA256 of each file using one worker per CPU,
+// unreadable files (broken links, permissions) keep an empty hash and are never cached
+func hashFileInfos(fileInfos []FileInfo) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				hash, err := calculateSHA256(fileInfos[i].Path)
+				if err != nil {
+					log.Debug().Err(err).Str("file", fileInfos[i].Path).Msg("file not hashed")
+					continue
+				}
+				fileInfos[i].Hash = hash
+			}
+		}()
+	}
+
+	for i := range fileInfos {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
 // WriteHashesToJSON writes the file hashes to a JSON file
 func WriteHashesToJSON(fileInfos []FileInfo, outputPath string) error {
 	if !debugOutput {
@@ -105,53 +138,6 @@ func FilterFilesByPattern(fileInfos []FileInfo, pattern string) ([]FileInfo, err
 	return filteredFiles, nil
 }
 
-func isIgnored(ignorePaths []string, path string) bool {
-	absPath, err := filepath.Abs(path)
-	if err != nil {
-		// If the path cannot be converted to an absolute path, assume it's not ignored
-		return false
-	}
-
-	// Normalize the path separators
-	absPath = filepath.ToSlash(absPath)
-
-	for _, ignore := range ignorePaths {
-		ignore = filepath.ToSlash(ignore)
-
-		// Handle file extension patterns like *.md
-		if strings.HasPrefix(ignore, "*.") {
-			ext := strings.ToLower(ignore[1:]) // ".md" for "*.md"
-			if strings.EqualFold(filepath.Ext(absPath), ext) {
-				return true
-			}
-		} else if strings.HasSuffix(ignore, "/") {
-			// Handle directory ignore patterns (like dist/)
-			ignoreDir := ignore[:len(ignore)-1] // Remove trailing slash
-			pathParts := strings.Split(absPath, "/")
-			for _, part := range pathParts {
-				if part == ignoreDir {
-					return true
-				}
-			}
-		} else {
-			// For other patterns, check if the path contains the ignore pattern
-			if strings.Contains(absPath, ignore) {
-				return true
-			}
-		}
-	}
-
-	return false
-}
-
-func processIgnorePatterns(ignorePatterns []string) []string {
-	processedPatterns := make([]string, 0, len(ignorePatterns)*2)
-	for _, pattern := range ignorePatterns {
-		processedPatterns = append(processedPatterns, "-g", fmt.Sprintf("!%s", pattern))
-	}
-	return processedPatterns
-}
-
 func detectOverlap(paths []string, targetPath string) (bool, string) {
 	// Normalize the target path
 	targetPath = filepath.Clean(targetPath)
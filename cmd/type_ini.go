@@ -20,7 +20,7 @@ func ProcessINIType(policy Policy, targetDir string, filePaths []string) error {
 		}
 
 		cueContent := policy.Schema.Structure
-		valid, issues := validateContentAndCUE(iniContent, cueContent, "ini", policy.Schema.Strict, policy.ID)
+		valid, issues := validateContentAndCUE(iniContent, cueContent, policyFormat(policy, "ini"), policy.Schema.Strict, policy.ID)
 
 		// Generate results for this file
 		fileResults := generateSchemaResults(policy, filePath, valid, issues, false)
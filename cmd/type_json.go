@@ -17,7 +17,7 @@ func ProcessJSONType(policy Policy, targetDir string, filePaths []string) error
 		}
 
 		cueContent := policy.Schema.Structure
-		valid, issues := validateContentAndCUE(jsonContent, cueContent, "json", policy.Schema.Strict, policy.ID)
+		valid, issues := validateContentAndCUE(jsonContent, cueContent, policyFormat(policy, "json"), policy.Schema.Strict, policy.ID)
 
 		// Generate results for this file
 		fileResults := generateSchemaResults(policy, filePath, valid, issues, false)
@@ -63,6 +63,10 @@ func ProcessJSONType(policy Policy, targetDir string, filePaths []string) error
 }
 
 func ProcessJSONTypeWithPatch(policy Policy, targetDir string, filePaths []string) error {
+	if format := policyFormat(policy, "json"); format != "json" {
+		return processGenericType(policy, filePaths, format)
+	}
+
 	var allResults []Result
 
 	// Create _patched directory if it doesn't exist
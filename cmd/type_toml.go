@@ -16,7 +16,7 @@ func ProcessTOMLType(policy Policy, targetDir string, filePaths []string) error
 		}
 
 		cueContent := policy.Schema.Structure
-		valid, issues := validateContentAndCUE(tomlContent, cueContent, "toml", policy.Schema.Strict, policy.ID)
+		valid, issues := validateContentAndCUE(tomlContent, cueContent, policyFormat(policy, "toml"), policy.Schema.Strict, policy.ID)
 
 		// Generate results for this file
 		fileResults := generateSchemaResults(policy, filePath, valid, issues, false)
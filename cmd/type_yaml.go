@@ -1,10 +1,30 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
+// yamlDocument is one non-empty document of a YAML stream, index counts them from 0
+type yamlDocument struct {
+	index      int
+	line       int
+	apiVersion string
+	kind       string
+	name       string
+	root       *yaml.Node
+}
+
 func ProcessYAMLType(policy Policy, targetDir string, filePaths []string) error {
 	var allResults []Result
 
@@ -15,8 +35,22 @@ func ProcessYAMLType(policy Policy, targetDir string, filePaths []string) error
 			return fmt.Errorf("error reading YAML file %s: %w", filePath, err)
 		}
 
+		// multi-document files (Kubernetes manifests, rendered Helm charts) are validated document by document
+		format := policyFormat(policy, "yaml")
+		if format == "yaml" {
+			documents, err := splitYAMLDocuments(yamlContent)
+			if err != nil || len(documents) > 1 || !policy.Schema.Documents.empty() {
+				allResults = append(allResults, validateYAMLDocuments(policy, filePath, documents)...)
+				var decodeErr *yamlDecodeError
+				if errors.As(err, &decodeErr) {
+					allResults = append(allResults, yamlDecodeResults(policy, filePath, decodeErr)...)
+				}
+				continue
+			}
+		}
+
 		cueContent := policy.Schema.Structure
-		valid, issues := validateContentAndCUE(yamlContent, cueContent, "yaml", policy.Schema.Strict, policy.ID)
+		valid, issues := validateContentAndCUE(yamlContent, cueContent, format, policy.Schema.Strict, policy.ID)
 
 		// Generate results for this file
 		fileResults := generateSchemaResults(policy, filePath, valid, issues, false)
@@ -61,3 +95,202 @@ func ProcessYAMLType(policy Policy, targetDir string, filePaths []string) error
 
 	return nil
 }
+
+// yamlDecodeError is a syntax error in a YAML stream, index is the document holding it and line
+// the line reported by the parser or else the start of that document
+type yamlDecodeError struct {
+	index int
+	line  int
+	err   error
+}
+
+func (e *yamlDecodeError) Error() string {
+	return e.err.Error()
+}
+
+// splitYAMLDocuments reads every document of a YAML stream, empty documents are skipped. On a syntax
+// error the documents read before it are returned with a *yamlDecodeError
+func splitYAMLDocuments(content []byte) ([]yamlDocument, error) {
+	var documents []yamlDocument
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for read := 0; ; read++ {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				return documents, nil
+			}
+			decodeErr := &yamlDecodeError{index: len(documents), line: yamlDocumentStart(content, read), err: err}
+			if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+				decodeErr.line, _ = strconv.Atoi(match[1])
+			}
+			return documents, decodeErr
+		}
+		if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			continue
+		}
+		root := node.Content[0]
+		documents = append(documents, yamlDocument{
+			index:      len(documents),
+			line:       root.Line,
+			apiVersion: yamlScalar(root, "apiVersion"),
+			kind:       yamlScalar(root, "kind"),
+			name:       yamlScalar(yamlMappingValue(root, "metadata"), "name"),
+			root:       root,
+		})
+	}
+}
+
+// yamlDocumentStart returns the line where the document read in position n of the stream starts,
+// from the "---" markers. A stream without a leading marker starts its first document on line 1
+func yamlDocumentStart(content []byte, n int) int {
+	var markers []int
+	leading := true
+	for i, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if line == "---" || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "---\t") {
+			markers = append(markers, i+1)
+		} else if len(markers) == 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "%") {
+			leading = false
+		}
+	}
+	if !leading {
+		n--
+	}
+	if n < 0 || n >= len(markers) {
+		return 1
+	}
+	return markers[n]
+}
+
+func (s DocumentSelector) empty() bool {
+	return len(s.APIVersion) == 0 && len(s.Kind) == 0
+}
+
+func (s DocumentSelector) selects(document yamlDocument) bool {
+	return matchDocumentField(s.APIVersion, document.apiVersion) && matchDocumentField(s.Kind, document.kind)
+}
+
+func matchDocumentField(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// validateYAMLDocuments validates the selected documents one by one, results are located on the
+// line of the failing field and carry the document index
+func validateYAMLDocuments(policy Policy, filePath string, documents []yamlDocument) []Result {
+	var results []Result
+	sources := sourceLines{}
+	for _, document := range documents {
+		if !policy.Schema.Documents.selects(document) {
+			continue
+		}
+
+		var issues []schemaIssue
+		jsonContent, err := yamlNodeJSON(document.root)
+		if err != nil {
+			issues = append(issues, schemaIssue{message: fmt.Sprintf("Error converting yaml to JSON: %v", err)})
+		} else {
+			_, issues = validateJSONWithCUE(jsonContent, policy.Schema.Structure, policy.Schema.Strict, policy.ID)
+		}
+
+		var messages []string
+		for _, issue := range issues {
+			messages = append(messages, issue.message)
+		}
+		documentResults := generateSchemaResults(policy, filePath, len(issues) == 0, messages, false)
+
+		// issue results come first, then the summary of the document
+		for i := range documentResults {
+			node := document.root
+			if i < len(issues) {
+				node = yamlNodeAt(document.root, issues[i].path)
+			}
+			locateYAMLDocumentResult(&documentResults[i], document, node, filePath, sources, policy.ContextLines)
+		}
+		results = append(results, documentResults...)
+
+		if len(issues) > 0 {
+			log.Debug().Msgf("Policy %s validation failed for document %d of file %s ", policy.ID, document.index, filePath)
+		}
+	}
+	return results
+}
+
+// yamlDecodeResults reports a syntax error of a YAML stream as a violation on its document and line
+func yamlDecodeResults(policy Policy, filePath string, decodeErr *yamlDecodeError) []Result {
+	document := yamlDocument{index: decodeErr.index, line: decodeErr.line}
+	node := &yaml.Node{Line: decodeErr.line, Column: 1}
+	results := generateSchemaResults(policy, filePath, false, []string{fmt.Sprintf("invalid YAML: %v", decodeErr.err)}, false)
+	sources := sourceLines{}
+	for i := range results {
+		locateYAMLDocumentResult(&results[i], document, node, filePath, sources, policy.ContextLines)
+	}
+	log.Debug().Msgf("Policy %s found invalid YAML in document %d of file %s ", policy.ID, decodeErr.index, filePath)
+	return results
+}
+
+func yamlNodeJSON(node *yaml.Node) ([]byte, error) {
+	var content interface{}
+	if err := node.Decode(&content); err != nil {
+		return nil, err
+	}
+	return json.Marshal(content)
+}
+
+// yamlNodeAt follows a field path (keys and list indexes) and returns the deepest node found
+func yamlNodeAt(root *yaml.Node, fieldPath []string) *yaml.Node {
+	node := root
+	for _, selector := range fieldPath {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			next = yamlMappingValue(node, selector)
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(selector); err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return node
+}
+
+func locateYAMLDocumentResult(result *Result, document yamlDocument, node *yaml.Node, filePath string, sources sourceLines, contextLines int) {
+	label := fmt.Sprintf("document[%d]", document.index)
+	name := strings.Trim(document.kind+"/"+document.name, "/")
+	if name != "" {
+		label += ":" + name
+	}
+	result.Message.Text = fmt.Sprintf("%s (%s)", result.Message.Text, label)
+
+	region := Region{StartLine: node.Line, StartColumn: node.Column, EndLine: node.Line, EndColumn: node.Column}
+	if lines := sources.get(filePath); node.Line >= 1 && node.Line <= len(lines) {
+		line := strings.TrimRight(lines[node.Line-1], "\r\n")
+		region.StartColumn = 1
+		region.EndColumn = len(line) + 1
+		region.Snippet = Snippet{Text: strings.TrimSpace(line)}
+	}
+
+	result.Locations = []Location{{
+		PhysicalLocation: PhysicalLocation{
+			ArtifactLocation: ArtifactLocation{URI: filepath.ToSlash(filePath)},
+			Region:           region,
+			ContextRegion:    sources.contextRegion(filePath, region, contextLines),
+		},
+		LogicalLocations: []LogicalLocation{{Name: name, FullyQualifiedName: label, Kind: "object"}},
+	}}
+
+	index := document.index
+	result.Properties.DocumentIndex = &index
+	result.Properties.ResourceType = document.kind
+}
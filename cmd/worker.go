@@ -6,10 +6,10 @@ import (
 	"github.com/gookit/event"
 )
 
-func preparePolicyPaths(policy Policy, allFileInfos []FileInfo) []string {
+func preparePolicyPaths(policy Policy, allFileInfos []FileInfo) ([]string, error) {
 
-	_, filePaths := filterFiles(policy, allFileInfos)
-	return filePaths
+	_, filePaths, err := filterFiles(policy, allFileInfos)
+	return filePaths, err
 }
 
 // processPolicyInWorker handles policy processing based on the policy type
@@ -65,6 +65,16 @@ func processPolicyInWorker(e event.Event, policyType string) error {
 		return ProcessINIType(policy, targetDir, filePaths)
 	case "rego":
 		return ProcessRegoType(policy, targetDir, filePaths)
+	case "secrets":
+		return ProcessSecretsType(policy, targetDir, filePaths)
+	case "dependencies":
+		return ProcessDependenciesType(policy, targetDir, filePaths)
+	case "license":
+		return ProcessLicenseType(policy, targetDir, filePaths)
+	case "dockerfile":
+		return ProcessDockerfileType(policy, targetDir, filePaths)
+	case "hcl":
+		return ProcessHCLType(policy, targetDir, filePaths)
 	default:
 		return fmt.Errorf("unsupported policy type: %s", policyType)
 	}
@@ -58,6 +58,11 @@ export default defineConfig({
           { text: 'ASSURE - API ', link: '/docs/policy-assure-api' },
           { text: 'ASSURE - REGO ', link: '/docs/policy-assure-rego' },
           { text: 'RUNTIME ', link: '/docs/policy-runtime' },
+          { text: 'SECRETS ', link: '/docs/policy-secrets' },
+          { text: 'DEPENDENCIES ', link: '/docs/policy-dependencies' },
+          { text: 'LICENSE ', link: '/docs/policy-license' },
+          { text: 'DOCKERFILE ', link: '/docs/policy-dockerfile' },
+          { text: 'HCL / TERRAFORM ', link: '/docs/policy-hcl' },
 
         ]
       },
@@ -67,6 +72,7 @@ export default defineConfig({
 
           { text: 'Schema', link: '/docs/policy-schema' },
           { text: 'Enforcement Levels', link: '/docs/enforcement' },
+          { text: 'Policy Tooling', link: '/docs/policy-tooling' },
         ]
       },
       {
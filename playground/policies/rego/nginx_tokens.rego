@@ -0,0 +1,11 @@
+package nginx
+
+import rego.v1
+
+violations contains sprintf("server_tokens %s on line %d", [d.args[0], d.line]) if {
+	some http in input.http
+	some d in http.block.server_tokens
+	d.args[0] != "off"
+}
+
+allow := count(violations) == 0
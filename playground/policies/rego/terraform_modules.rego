@@ -0,0 +1,11 @@
+package terraform
+
+import rego.v1
+
+violations contains {"msg": sprintf("module %s source is not pinned to a ref", [name]), "address": sprintf("module.%s", [name])} if {
+	some name, module in input.module
+	not contains(module.source, "?ref=")
+	not module.version
+}
+
+allow := count(violations) == 0
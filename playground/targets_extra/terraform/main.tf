@@ -0,0 +1,39 @@
+terraform {
+  required_version = ">= 1.5"
+}
+
+provider "aws" {
+  region = "eu-west-1"
+}
+
+provider "aws" {
+  alias  = "us"
+  region = "us-east-1"
+}
+
+locals {
+  env = "prod"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs-${local.env}"
+  acl    = "public-read"
+
+  versioning {
+    enabled = false
+  }
+}
+
+resource "aws_s3_bucket" "assets" {
+  bucket = "assets-${local.env}"
+  acl    = "private"
+
+  versioning {
+    enabled = true
+  }
+}
+
+module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+  cidr   = var.cidr
+}